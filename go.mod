@@ -1,3 +1,5 @@
 module golang.org/dl
 
 go 1.18
+
+require golang.org/x/mod v0.20.0
@@ -0,0 +1,185 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The gensums command compiles the SHA-256 of the release archives of Go
+// versions, from the release index, into their wrapper commands.
+//
+// Usage:
+//
+//	go run ./internal/gensums [-index=URL or file] [-all | version...]
+//
+// For each version, such as go1.22.3, it writes the SHA-256 of the binary
+// and source archives of the version to version/sums.go, and makes
+// version/main.go pass them to version.RunWithChecksums. With -all, it
+// does so for every wrapper command of a version in the release index, so
+// that it can be run whenever wrappers are added. It must be run from the
+// root of the repository.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	indexFlag = flag.String("index", "https://go.dev/dl/?mode=json&include=all", "URL or file name of the release index")
+	allFlag   = flag.Bool("all", false, "generate the checksums of every wrapper command of a released version")
+)
+
+// A release is an entry of the release index.
+type release struct {
+	Version string `json:"version"`
+	Files   []struct {
+		Filename string `json:"filename"`
+		SHA256   string `json:"sha256"`
+		Kind     string `json:"kind"`
+	} `json:"files"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gensums: ")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gensums [-index=URL or file] [-all | version...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if (flag.NArg() == 0) != *allFlag {
+		flag.Usage()
+		os.Exit(2)
+	}
+	index, err := readIndex(*indexFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	versions := flag.Args()
+	if *allFlag {
+		if versions, err = releasedWrappers(".", index); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, version := range versions {
+		if err := generate(version, version, index); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// readIndex reads the release index from the given URL or file.
+func readIndex(name string) ([]release, error) {
+	var data []byte
+	if strings.HasPrefix(name, "https://") || strings.HasPrefix(name, "http://") {
+		res, err := http.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %v", name, res.Status)
+		}
+		if data, err = io.ReadAll(res.Body); err != nil {
+			return nil, fmt.Errorf("reading %s: %v", name, err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(name); err != nil {
+			return nil, err
+		}
+	}
+	var index []release
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", name, err)
+	}
+	return index, nil
+}
+
+// releasedWrappers returns the versions of the wrapper commands in dir
+// that are listed in index, in order.
+func releasedWrappers(dir string, index []release) ([]string, error) {
+	released := make(map[string]bool)
+	for _, r := range index {
+		released[r.Version] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, e := range entries {
+		if !e.IsDir() || !released[e.Name()] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), "main.go")); err == nil {
+			versions = append(versions, e.Name())
+		}
+	}
+	return versions, nil
+}
+
+// generate writes the SHA-256 of the archives of version in index to
+// sums.go in dir, the directory of its wrapper command, and makes its
+// main.go use them.
+func generate(dir, version string, index []release) error {
+	sums := make(map[string]string)
+	for _, r := range index {
+		if r.Version != version {
+			continue
+		}
+		for _, f := range r.Files {
+			if f.Kind == "archive" || f.Kind == "source" {
+				sums[f.Filename] = f.SHA256
+			}
+		}
+	}
+	if len(sums) == 0 {
+		return fmt.Errorf("no archives of %s in release index", version)
+	}
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by golang.org/dl/internal/gensums; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package main\n\n")
+	fmt.Fprintf(&buf, "// archiveSHA256 holds the SHA-256 of the release archives of %s.\n", version)
+	fmt.Fprintf(&buf, "var archiveSHA256 = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%q: %q,\n", name, sums[name])
+	}
+	fmt.Fprintf(&buf, "}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sums.go"), src, 0644); err != nil {
+		return err
+	}
+
+	mainFile := filepath.Join(dir, "main.go")
+	main, err := os.ReadFile(mainFile)
+	if err != nil {
+		return err
+	}
+	call := fmt.Sprintf("version.Run(%q)", version)
+	withSums := fmt.Sprintf("version.RunWithChecksums(%q, archiveSHA256)", version)
+	switch {
+	case bytes.Contains(main, []byte(withSums)):
+		return nil
+	case !bytes.Contains(main, []byte(call)):
+		return fmt.Errorf("%s: no call to %s", mainFile, call)
+	}
+	return os.WriteFile(mainFile, bytes.Replace(main, []byte(call), []byte(withSums), 1), 0644)
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testIndex = `[
+	{"version": "go1.99", "files": [
+		{"filename": "go1.99.src.tar.gz", "sha256": "1111", "kind": "source"},
+		{"filename": "go1.99.linux-amd64.tar.gz", "sha256": "2222", "kind": "archive"},
+		{"filename": "go1.99.windows-amd64.msi", "sha256": "3333", "kind": "installer"}
+	]},
+	{"version": "go1.98", "files": [
+		{"filename": "go1.98.linux-amd64.tar.gz", "sha256": "4444", "kind": "archive"}
+	]}
+]`
+
+func TestGenerate(t *testing.T) {
+	var index []release
+	if err := json.Unmarshal([]byte(testIndex), &index); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	main := "package main\n\nimport \"golang.org/dl/internal/version\"\n\nfunc main() {\n\tversion.Run(\"go1.99\")\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Generating twice leaves the same result.
+	for i := 0; i < 2; i++ {
+		if err := generate(dir, "go1.99", index); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sums, err := os.ReadFile(filepath.Join(dir, "sums.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"go1.99.src.tar.gz":         "1111"`, `"go1.99.linux-amd64.tar.gz": "2222"`} {
+		if !strings.Contains(string(sums), want) {
+			t.Errorf("sums.go does not contain %s:\n%s", want, sums)
+		}
+	}
+	for _, unwanted := range []string{"3333", "4444"} {
+		if strings.Contains(string(sums), unwanted) {
+			t.Errorf("sums.go contains %s:\n%s", unwanted, sums)
+		}
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `version.RunWithChecksums("go1.99", archiveSHA256)`; strings.Count(string(got), want) != 1 {
+		t.Errorf("main.go does not call %s:\n%s", want, got)
+	}
+
+	if err := generate(dir, "go1.97", index); err == nil {
+		t.Errorf("generate succeeded for a version missing from the index")
+	}
+}
+
+func TestReleasedWrappers(t *testing.T) {
+	var index []release
+	if err := json.Unmarshal([]byte(testIndex), &index); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"go1.97", "go1.98", "go1.99", "gotip"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "main.go"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := releasedWrappers(dir, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go1.98", "go1.99"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("releasedWrappers = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// An archiveFormat is a format in which release archives may be served.
+// The go.dev releases are tar.gz files, or zip files for Windows, but
+// mirrors may recompress them.
+type archiveFormat struct {
+	ext   string // file name extension, such as ".tar.gz"
+	magic string // leading bytes of the file
+
+	// decompress returns the tar stream of the archive read from r. It is
+	// nil for zip files.
+	decompress func(r io.Reader) (io.ReadCloser, error)
+
+	// stream reports whether the archive may be unpacked as it downloads,
+	// before it is verified. Archives decompressed by a command are only
+	// unpacked once verified, so that the command never reads untrusted
+	// input.
+	stream bool
+
+	// command is the command of the host that decompresses the archive,
+	// if this package can't.
+	command string
+}
+
+// The xz and zstd formats are decompressed by the commands of the host,
+// which must be installed to unpack them.
+var archiveFormats = []*archiveFormat{
+	{ext: ".tar.gz", magic: "\x1f\x8b", decompress: gunzip, stream: true},
+	{ext: ".zip", magic: "PK"},
+	{ext: ".tar.xz", magic: "\xfd7zXZ\x00", decompress: commandDecompressor("xz"), command: "xz"},
+	{ext: ".tar.zst", magic: "\x28\xb5\x2f\xfd", decompress: commandDecompressor("zstd"), command: "zstd"},
+}
+
+// checkCommand returns an error if the archive can't be unpacked on this
+// host because the command that decompresses it isn't installed, so that
+// it isn't downloaded for nothing. It returns nil for a nil format.
+func (f *archiveFormat) checkCommand() error {
+	if f == nil || f.command == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(f.command); err != nil {
+		return fmt.Errorf("unpacking %s archives requires the %s command, which is not installed", f.ext, f.command)
+	}
+	return nil
+}
+
+// maxMagic is the length of the longest magic in archiveFormats.
+const maxMagic = 6
+
+// recompressedExts are the extensions under which installFrom also looks
+// for an archive that a mirror doesn't serve under its release name, if
+// it is verified against the .sha256 file of the mirror: the release index
+// and the checksums compiled into wrappers only list release names.
+var recompressedExts = []string{".tar.zst", ".tar.xz"}
+
+// formatOfName returns the archive format of the named file by its
+// extension, or nil if it isn't a known format.
+func formatOfName(name string) *archiveFormat {
+	for _, f := range archiveFormats {
+		if strings.HasSuffix(name, f.ext) {
+			return f
+		}
+	}
+	return nil
+}
+
+// formatOfMagic returns the archive format of a file starting with the
+// given bytes, or nil if it isn't a known format.
+func formatOfMagic(magic []byte) *archiveFormat {
+	for _, f := range archiveFormats {
+		if bytes.HasPrefix(magic, []byte(f.magic)) {
+			return f
+		}
+	}
+	return nil
+}
+
+// formatOfFile returns the archive format of the named file by its
+// contents, whatever its name.
+func formatOfFile(file string) (*archiveFormat, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	magic := make([]byte, maxMagic)
+	n, err := io.ReadFull(r, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	f := formatOfMagic(magic[:n])
+	if f == nil {
+		return nil, fmt.Errorf("%s is not a zip, tar.gz, tar.xz or tar.zst file", file)
+	}
+	return f, nil
+}
+
+// errZipStream is returned by decompress for a zip archive, which can't
+// be read as a stream.
+var errZipStream = errors.New("zip archive can't be unpacked as a stream")
+
+// decompress returns the tar stream of the compressed tar archive read
+// from r, detecting its format from its leading bytes.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(maxMagic)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	f := formatOfMagic(magic)
+	switch {
+	case f == nil:
+		return nil, errors.New("not a tar.gz, tar.xz or tar.zst archive")
+	case f.decompress == nil:
+		return nil, errZipStream
+	}
+	return f.decompress(br)
+}
+
+func gunzip(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// commandDecompressor returns a decompress function that runs the named
+// command, such as xz or zstd, with the -dc flags.
+func commandDecompressor(name string) func(io.Reader) (io.ReadCloser, error) {
+	return func(r io.Reader) (io.ReadCloser, error) {
+		cmd := exec.Command(name, "-dc")
+		cmd.Stdin = r
+		cmd.Stderr = new(bytes.Buffer)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("decompressing archive requires %s: %v", name, err)
+		}
+		return &commandReader{ReadCloser: out, cmd: cmd}, nil
+	}
+}
+
+// A commandReader reads the standard output of a decompressor command.
+type commandReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Close reads the rest of the output, so that the command isn't killed
+// by a broken pipe, and waits for it to exit.
+func (r *commandReader) Close() error {
+	io.Copy(io.Discard, r.ReadCloser)
+	if err := r.cmd.Wait(); err != nil {
+		name := r.cmd.Args[0]
+		if stderr := strings.TrimSpace(r.cmd.Stderr.(*bytes.Buffer).String()); stderr != "" {
+			return fmt.Errorf("%s: %v: %s", name, err, stderr)
+		}
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recompress returns the tar.gz archive recompressed by the named
+// command, such as xz or zstd, skipping the test if it isn't installed.
+func recompress(t *testing.T, archive []byte, command string) []byte {
+	t.Helper()
+	if _, err := exec.LookPath(command); err != nil {
+		t.Skipf("%s not found: %v", command, err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(command, "-c")
+	cmd.Stdin = zr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%s: %v", command, err)
+	}
+	return out
+}
+
+func TestFormatOfMagic(t *testing.T) {
+	tests := []struct {
+		magic string
+		want  string
+	}{
+		{"\x1f\x8b\x08\x00", ".tar.gz"},
+		{"PK\x03\x04", ".zip"},
+		{"PK\x05\x06", ".zip"},
+		{"\xfd7zXZ\x00", ".tar.xz"},
+		{"\x28\xb5\x2f\xfd\x04", ".tar.zst"},
+		{"\xfd7zX", ""},
+		{"go1.99", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if f := formatOfMagic([]byte(tt.magic)); f != nil {
+			got = f.ext
+		}
+		if got != tt.want {
+			t.Errorf("formatOfMagic(%q) = %q; want %q", tt.magic, got, tt.want)
+		}
+	}
+}
+
+func TestUnpackRecompressed(t *testing.T) {
+	archive := craftTarGz(t, []testEntry{
+		{"go/", 0755 | os.ModeDir, "", ""},
+		{"go/VERSION", 0644, "go1.99", ""},
+		{"go/bin/go", 0755, "#!/bin/sh\n", ""},
+	})
+	for _, command := range []string{"xz", "zstd"} {
+		t.Run(command, func(t *testing.T) {
+			data := recompress(t, archive, command)
+			// Name the archive like the release it was recompressed
+			// from: the format is detected from its contents.
+			dir := t.TempDir()
+			file := filepath.Join(dir, "go1.99.linux-amd64.tar.gz")
+			if err := os.WriteFile(file, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			target := filepath.Join(dir, "go")
+			if err := unpackArchive(target, file, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := os.ReadFile(filepath.Join(target, "VERSION")); err != nil || string(got) != "go1.99" {
+				t.Errorf("VERSION = %q, %v; want %q", got, err, "go1.99")
+			}
+			if got, err := archiveVersion(file); err != nil || got != "go1.99" {
+				t.Errorf("archiveVersion = %q, %v; want %q", got, err, "go1.99")
+			}
+
+			// The decompressor reports a truncated archive even if the
+			// tar stream ends at an entry boundary.
+			if err := os.WriteFile(file, data[:len(data)-4], 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := unpackArchive(filepath.Join(dir, "truncated"), file, nil); err == nil {
+				t.Error("unpacking truncated archive succeeded")
+			}
+		})
+	}
+}
+
+func TestInstallRecompressedMirror(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows releases are zip files")
+	}
+	archive := recompress(t, testArchive(t, map[string]string{"VERSION": "go1.99"}), "zstd")
+	name := strings.TrimSuffix(versionArchiveName("go1.99"), ".tar.gz") + ".tar.zst"
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + name:
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(archive))
+		case "/" + name + ".sha256":
+			fmt.Fprintf(w, "%x\n", sha256.Sum256(archive))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "VERSION")); err != nil || string(got) != "go1.99" {
+		t.Errorf("VERSION = %q, %v; want %q", got, err, "go1.99")
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || !bytes.Equal(got, archive) {
+		t.Errorf("kept archive %s differs from the downloaded one: %v", name, err)
+	}
+}
+
+func TestInstallRecompressedVerifiedFirst(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows releases are zip files")
+	}
+	archive := recompress(t, testArchive(t, map[string]string{"VERSION": "go1.99"}), "xz")
+	for _, good := range []bool{false, true} {
+		t.Run(fmt.Sprintf("good=%v", good), func(t *testing.T) {
+			// The mirror serves the archive under its release name.
+			sum := sha256.Sum256(archive)
+			if !good {
+				sum = sha256.Sum256(nil)
+			}
+			name := versionArchiveName("go1.99")
+			mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/" + name:
+					http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(archive))
+				case "/" + name + ".sha256":
+					fmt.Fprintf(w, "%x\n", sum)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer mirror.Close()
+			t.Setenv("GODL_MIRRORS", mirror.URL)
+
+			dir := filepath.Join(tempSDKDir(t), "go1.99")
+			err := install(dir, "go1.99", hostPlatform())
+			if !good {
+				if err == nil {
+					t.Fatal("install of corrupt archive succeeded")
+				}
+				// Nothing was decompressed before the archive was verified.
+				if _, err := os.Stat(filepath.Join(dir+stagingSuffix, "VERSION")); !os.IsNotExist(err) {
+					t.Errorf("corrupt archive was unpacked: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := os.ReadFile(filepath.Join(dir, "VERSION")); err != nil || string(got) != "go1.99" {
+				t.Errorf("VERSION = %q, %v; want %q", got, err, "go1.99")
+			}
+		})
+	}
+}
+
+func TestInstallRecompressedNoCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows releases are zip files")
+	}
+	t.Setenv("PATH", t.TempDir())
+	stem := strings.TrimSuffix(versionArchiveName("go1.99"), ".tar.gz")
+	var requests []string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+	t.Setenv("GODL_BUILD_SOURCE", "0")
+
+	// Recompressed archives aren't looked for without their command.
+	err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform())
+	if !errors.As(err, new(*noReleaseError)) {
+		t.Errorf("install = %v; want no release error", err)
+	}
+	for _, p := range requests {
+		if strings.HasPrefix(p, "/"+stem+".tar.xz") || strings.HasPrefix(p, "/"+stem+".tar.zst") {
+			t.Errorf("requested %s without a command to unpack it", p)
+		}
+	}
+
+	// Nor are they installed from a file.
+	file := filepath.Join(t.TempDir(), stem+".tar.zst")
+	if err := os.WriteFile(file, []byte("\x28\xb5\x2f\xfd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = installLocal(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", file)
+	if err == nil || !strings.Contains(err.Error(), "requires the zstd command") {
+		t.Errorf("installLocal = %v; want error about missing zstd", err)
+	}
+}
+
+func TestDecompressNotTar(t *testing.T) {
+	for _, data := range []string{"PK\x03\x04", "go1.99\n"} {
+		if rc, err := decompress(strings.NewReader(data)); err == nil {
+			io.Copy(io.Discard, rc)
+			rc.Close()
+			t.Errorf("decompress(%q) succeeded", data)
+		}
+	}
+}
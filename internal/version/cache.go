@@ -0,0 +1,258 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultCacheMaxSize is the default size limit of the archive cache.
+const defaultCacheMaxSize = 1 << 30
+
+// cacheNamesDir is the directory of the archive cache recording the SHA-256
+// of the archives added to it, by name.
+const cacheNamesDir = "names"
+
+// An archiveCache is a directory of verified release archives shared by all
+// SDK installs, named by their SHA-256 and format extension, such as
+// "<sha256>.tar.gz". When the cache grows beyond its maximum size, the
+// least recently used archives are removed.
+//
+// Archives copied into the directory under any other name, such as
+// "go1.22.3.linux-amd64.tar.gz", seed the cache: they are renamed after
+// their SHA-256 the next time the cache is used, and the install verifies
+// them like any other archive before using them.
+//
+// The SHA-256 of each archive added to the cache is also recorded in its
+// "names" directory by the name of the archive, so that an archive verified
+// before can be found without going to the network.
+type archiveCache struct {
+	dir     string
+	maxSize int64
+}
+
+// openArchiveCache returns the archive cache in the "archives" directory of
+// cacheDir, limited to the size set by $GODL_CACHE_MAX_SIZE. It returns nil
+// if the cache is disabled by GODL_CACHE_MAX_SIZE=0 or unavailable.
+func openArchiveCache() *archiveCache {
+	maxSize := int64(defaultCacheMaxSize)
+	if s := os.Getenv("GODL_CACHE_MAX_SIZE"); s == "0" {
+		return nil
+	} else if s != "" {
+		n, err := parseSize(s)
+		if err != nil {
+			log.Printf("ignoring invalid GODL_CACHE_MAX_SIZE=%q: %v", s, err)
+		} else {
+			maxSize = int64(n)
+		}
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return nil
+	}
+	dir = filepath.Join(dir, "archives")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("not using download cache: %v", err)
+		return nil
+	}
+	return &archiveCache{dir: dir, maxSize: maxSize}
+}
+
+// archiveExt returns the extension of the archive format of the named
+// file, or the empty string if it isn't a known format.
+func archiveExt(name string) string {
+	if f := formatOfName(name); f != nil {
+		return f.ext
+	}
+	return ""
+}
+
+// path returns the name of the cached archive with the given SHA-256, in
+// the format of the archive named name.
+func (c *archiveCache) path(sha, name string) string {
+	return filepath.Join(c.dir, sha+archiveExt(name))
+}
+
+// lookup returns the name of the cached archive with the given SHA-256 in
+// the format of the archive named name, or the empty string if there is
+// none.
+func (c *archiveCache) lookup(sha, name string) string {
+	if c == nil || archiveExt(name) == "" {
+		return ""
+	}
+	c.seed()
+	file := c.path(sha, name)
+	if _, err := os.Stat(file); err != nil {
+		return ""
+	}
+	now := time.Now()
+	os.Chtimes(file, now, now) // mark as recently used
+	return file
+}
+
+// sha returns the SHA-256 recorded for the archive named name when it was
+// added to the cache, or the empty string if it isn't in the cache.
+func (c *archiveCache) sha(name string) string {
+	if c == nil || archiveExt(name) == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, cacheNamesDir, name))
+	if err != nil {
+		return ""
+	}
+	sha := strings.TrimSpace(string(data))
+	if !isCacheKey(sha) {
+		return ""
+	}
+	if _, err := os.Stat(c.path(sha, name)); err != nil {
+		return ""
+	}
+	return sha
+}
+
+// tempFile returns the name of a file in the cache directory to download
+// the archive named name to, before adding it to the cache.
+func (c *archiveCache) tempFile(name string) string {
+	return filepath.Join(c.dir, name+".download")
+}
+
+// add adds file, a verified archive with the given SHA-256 in the format of
+// the archive named name, to the cache. If move is true, file is moved into
+// the cache; otherwise it is hard linked or copied. Failures are logged,
+// as the cache is only an optimization.
+func (c *archiveCache) add(file, sha, name string, move bool) {
+	if c == nil || archiveExt(name) == "" {
+		return
+	}
+	dst := c.path(sha, name)
+	var err error
+	if move {
+		err = os.Rename(file, dst)
+	} else {
+		err = linkOrCopy(file, dst)
+	}
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(c.dir, cacheNamesDir, name), []byte(sha+"\n"))
+	}
+	if err != nil {
+		log.Printf("adding %s to download cache: %v", name, err)
+		return
+	}
+	c.evict(dst)
+}
+
+// seed renames the archives copied into the cache directory by hand after
+// their SHA-256.
+func (c *archiveCache) seed() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		ext := archiveExt(name)
+		if !e.Type().IsRegular() || ext == "" || isCacheKey(strings.TrimSuffix(name, ext)) {
+			continue
+		}
+		file := filepath.Join(c.dir, name)
+		sha, err := fileSHA256(file)
+		if err != nil {
+			log.Printf("seeding download cache with %s: %v", name, err)
+			continue
+		}
+		if err := os.Rename(file, filepath.Join(c.dir, sha+ext)); err != nil {
+			log.Printf("seeding download cache with %s: %v", name, err)
+		}
+	}
+}
+
+// isCacheKey reports whether s is a hex-encoded SHA-256.
+func isCacheKey(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == 32 && strings.ToLower(s) == s
+}
+
+// evict removes the least recently used archives until the cache fits in
+// its maximum size. The archive named keep, just added, is never removed.
+func (c *archiveCache) evict(keep string) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type cached struct {
+		file  string
+		size  int64
+		mtime time.Time
+	}
+	var files []cached
+	var total int64
+	for _, e := range entries {
+		name := e.Name()
+		ext := archiveExt(name)
+		if ext == "" || !isCacheKey(strings.TrimSuffix(name, ext)) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cached{filepath.Join(c.dir, name), fi.Size(), fi.ModTime()})
+		total += fi.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime.Before(files[j].mtime) })
+	for _, f := range files {
+		if total <= c.maxSize {
+			break
+		}
+		if f.file == keep {
+			continue
+		}
+		if err := os.Remove(f.file); err != nil {
+			log.Printf("evicting from download cache: %v", err)
+			continue
+		}
+		total -= f.size
+	}
+}
+
+// linkOrCopy makes dst a hard link to src, or a copy of it if they can't
+// be linked, replacing dst if it exists.
+func linkOrCopy(src, dst string) error {
+	tmp := dst + ".tmp"
+	os.Remove(tmp)
+	if err := os.Link(src, tmp); err != nil {
+		if err := copyFile(src, tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dst)
+}
+
+// copyFile copies the contents of the file src to a new file dst.
+func copyFile(src, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("copying %s: %v", src, err)
+	}
+	return w.Close()
+}
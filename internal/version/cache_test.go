@@ -0,0 +1,135 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInstallFromCache(t *testing.T) {
+	t.Setenv("GODL_CACHE", t.TempDir())
+	t.Setenv("GODL_CACHE_MAX_SIZE", "")
+	t.Setenv("GODL_KEEP_ARCHIVE", "0")
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	var downloads int32
+	serve := serveArchive(archive, true)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && !strings.HasSuffix(r.URL.Path, ".sha256") {
+			atomic.AddInt32(&downloads, 1)
+		}
+		serve.ServeHTTP(w, r)
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	for i := 0; i < 2; i++ {
+		dir := filepath.Join(tempSDKDir(t), "go1.99")
+		if err := install(dir, "go1.99", hostPlatform()); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := os.ReadFile(filepath.Join(dir, "VERSION")); err != nil || string(got) != "go1.99" {
+			t.Errorf("VERSION = %q, %v; want %q", got, err, "go1.99")
+		}
+	}
+	if n := atomic.LoadInt32(&downloads); n != 1 {
+		t.Errorf("archive downloaded %d times; want 1", n)
+	}
+}
+
+func TestInstallFromCacheOffline(t *testing.T) {
+	t.Setenv("GODL_CACHE", t.TempDir())
+	t.Setenv("GODL_CACHE_MAX_SIZE", "")
+	t.Setenv("GODL_RETRY_ATTEMPTS", "1")
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	mirror := httptest.NewServer(serveArchive(archive, true))
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+	if err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The archive verified before is reinstalled without its .sha256 file.
+	mirror.Close()
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatalf("offline install from cache: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "VERSION")); err != nil || string(got) != "go1.99" {
+		t.Errorf("VERSION = %q, %v; want %q", got, err, "go1.99")
+	}
+}
+
+func TestArchiveCacheSeed(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("GODL_CACHE", cache)
+	t.Setenv("GODL_CACHE_MAX_SIZE", "")
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	name := versionArchiveName("go1.99")
+	if err := os.MkdirAll(filepath.Join(cache, "archives"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cache, "archives", name), archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Serve only the checksum.
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ".sha256") {
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "%x\n", sha256.Sum256(archive))
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(cache, "archives", fmt.Sprintf("%x%s", sha256.Sum256(archive), archiveExt(name)))
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("seeded archive not renamed after its SHA-256: %v", err)
+	}
+}
+
+func TestArchiveCacheEvict(t *testing.T) {
+	c := &archiveCache{dir: t.TempDir(), maxSize: 250}
+	var files []string
+	for i := 0; i < 3; i++ {
+		file := filepath.Join(c.dir, fmt.Sprintf("%x.tar.gz", sha256.Sum256([]byte{byte(i)})))
+		if err := os.WriteFile(file, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	other := filepath.Join(c.dir, "README")
+	if err := os.WriteFile(other, make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c.evict(files[0])
+	for i, file := range files {
+		_, err := os.Stat(file)
+		if exists := err == nil; exists != (i != 1) {
+			t.Errorf("archive %d exists = %v; want %v", i, exists, i != 1)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("evicted a file that isn't a cached archive: %v", err)
+	}
+}
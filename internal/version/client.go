@@ -0,0 +1,128 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// httpClient returns the HTTP client used for all requests made by
+// install. It is configured by the following environment variables:
+//
+//   - GODL_CA_FILE names a file of PEM-encoded certificates of root
+//     certificate authorities to trust in addition to the system ones,
+//     such as that of a TLS-intercepting proxy.
+//   - GODL_CLIENT_CERT and GODL_CLIENT_KEY name the PEM-encoded
+//     certificate and private key to present to servers that require
+//     client authentication. The key may be in the certificate file.
+//   - GODL_PROXY is the URL of the proxy to use for all requests. If unset,
+//     the proxy is selected by $HTTPS_PROXY, $HTTP_PROXY and $NO_PROXY.
+//
+// The timeouts are those returned by downloadTimeouts. URLs with the file
+// scheme, such as "file:///srv/go/go1.22.3.linux-amd64.tar.gz", name local
+// files.
+func httpClient() (*http.Client, error) {
+	tlsConfig, err := clientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if p := os.Getenv("GODL_PROXY"); p != "" {
+		u, err := url.Parse(p)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid GODL_PROXY=%q", p)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	connect, response, _ := downloadTimeouts()
+	t := &http.Transport{
+		// Archives are already compressed. Prefer accurate
+		// ContentLength. (Not that GCS would try to compress
+		// them, though)
+		DisableCompression:    true,
+		DisableKeepAlives:     true,
+		Proxy:                 proxy,
+		DialContext:           (&net.Dialer{Timeout: connect}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   connect,
+		ResponseHeaderTimeout: response,
+	}
+	t.RegisterProtocol("file", http.NewFileTransport(localFS{}))
+	return &http.Client{Transport: &userAgentTransport{t}}, nil
+}
+
+// localFS is the file system of file URLs, whose paths are absolute file
+// names with forward slashes.
+type localFS struct{}
+
+func (localFS) Open(name string) (http.File, error) {
+	if runtime.GOOS == "windows" {
+		// The path of file:///C:/go is /C:/go.
+		name = strings.TrimPrefix(name, "/")
+	}
+	return os.Open(filepath.FromSlash(name))
+}
+
+// fileURL returns the file URL of the named local file.
+func fileURL(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String(), nil
+}
+
+// clientTLSConfig returns the TLS configuration selected by $GODL_CA_FILE,
+// $GODL_CLIENT_CERT and $GODL_CLIENT_KEY, or nil for the default one.
+func clientTLSConfig() (*tls.Config, error) {
+	caFile := os.Getenv("GODL_CA_FILE")
+	certFile := os.Getenv("GODL_CLIENT_CERT")
+	keyFile := os.Getenv("GODL_CLIENT_KEY")
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	config := new(tls.Config)
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading GODL_CA_FILE: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in GODL_CA_FILE %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" {
+			return nil, fmt.Errorf("GODL_CLIENT_KEY is set without GODL_CLIENT_CERT")
+		}
+		if keyFile == "" {
+			keyFile = certFile
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertFile writes the PEM encoding of cert to a file and returns its
+// name.
+func writeCertFile(t *testing.T, cert *x509.Certificate) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "cert.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestHTTPClientCAFile(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	t.Setenv("GODL_RETRY_ATTEMPTS", "1")
+
+	if _, err := slurpURLToString(ts.URL); err == nil {
+		t.Fatal("request to server with unknown certificate authority succeeded")
+	}
+	t.Setenv("GODL_CA_FILE", writeCertFile(t, ts.Certificate()))
+	if got, err := slurpURLToString(ts.URL); err != nil || got != "ok" {
+		t.Errorf("slurpURLToString = %q, %v; want %q", got, err, "ok")
+	}
+}
+
+func TestHTTPClientCert(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "no client certificate", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+	t.Setenv("GODL_CA_FILE", writeCertFile(t, ts.Certificate()))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gopher"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both in the same file.
+	certFile := filepath.Join(t.TempDir(), "client.pem")
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})...)
+	if err := os.WriteFile(certFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GODL_CLIENT_CERT", certFile)
+
+	if got, err := slurpURLToString(ts.URL); err != nil || got != "gopher" {
+		t.Errorf("slurpURLToString = %q, %v; want %q", got, err, "gopher")
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+	t.Setenv("GODL_PROXY", proxy.URL)
+
+	const u = "http://example.invalid/go.sha256"
+	if got, err := slurpURLToString(u); err != nil || got != "proxied "+u {
+		t.Errorf("slurpURLToString = %q, %v; want %q", got, err, "proxied "+u)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && !(mips || mipsle || mips64 || mips64le || ppc64 || ppc64le)
+
+package version
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The ioctl requests FICLONE, _IOW(0x94, 9, int), and FS_IOC_FIEMAP,
+// _IOWR('f', 11, struct fiemap), whose encoding differs on the
+// architectures excluded by the build constraint.
+const (
+	ficlone     = 0x40049409
+	fsIocFiemap = 0xc020660b
+)
+
+// cloneFile creates dst with the given permissions as a reflink of src,
+// sharing its blocks until either is modified, on filesystems that support
+// it, such as Btrfs and XFS.
+func cloneFile(src, dst string, perm fs.FileMode) error {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	d, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, d.Fd(), ficlone, s.Fd())
+	if errno != 0 {
+		err = &os.PathError{Op: "ioctl FICLONE", Path: dst, Err: errno}
+	} else {
+		// The umask may have removed permissions.
+		err = d.Chmod(perm)
+	}
+	if closeErr := d.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// fiemap is struct fiemap, with room for a single extent.
+type fiemap struct {
+	start         uint64
+	length        uint64
+	flags         uint32
+	mappedExtents uint32
+	extentCount   uint32
+	reserved      uint32
+	extent        struct {
+		logical    uint64
+		physical   uint64
+		length     uint64
+		reserved64 [2]uint64
+		flags      uint32
+		reserved   [3]uint32
+	}
+}
+
+const (
+	fiemapFlagSync         = 0x1
+	fiemapExtentUnknown    = 0x1
+	fiemapExtentDataInline = 0x200
+)
+
+// sharesBlocks reports whether the files a and b start with the same
+// block on disk, as they do if one is a reflink of the other.
+func sharesBlocks(a, b string) bool {
+	pa, ok := firstBlock(a)
+	if !ok {
+		return false
+	}
+	pb, ok := firstBlock(b)
+	return ok && pa == pb
+}
+
+// firstBlock returns the physical offset of the first extent of file.
+func firstBlock(file string) (physical uint64, ok bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	fm := fiemap{length: ^uint64(0), flags: fiemapFlagSync, extentCount: 1}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fsIocFiemap, uintptr(unsafe.Pointer(&fm)))
+	if errno != 0 || fm.mappedExtents == 0 || fm.extent.flags&(fiemapExtentUnknown|fiemapExtentDataInline) != 0 {
+		return 0, false
+	}
+	return fm.extent.physical, fm.extent.physical != 0
+}
@@ -0,0 +1,20 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux || mips || mipsle || mips64 || mips64le || ppc64 || ppc64le
+
+package version
+
+import (
+	"errors"
+	"io/fs"
+)
+
+func cloneFile(src, dst string, perm fs.FileMode) error {
+	return errors.New("reflinks not supported")
+}
+
+func sharesBlocks(a, b string) bool {
+	return false
+}
@@ -0,0 +1,230 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// dedupeOnInstall reports whether to deduplicate the files of an SDK
+// against the other installed SDKs once it is installed, as set by
+// GODL_DEDUPE=1.
+func dedupeOnInstall() bool {
+	return os.Getenv("GODL_DEDUPE") == "1"
+}
+
+// dedupeInstalled deduplicates the files of the SDK just installed in
+// targetDir against the SDKs installed next to it, if enabled by
+// dedupeOnInstall. The install doesn't fail if that fails.
+func dedupeInstalled(targetDir string) {
+	if !dedupeOnInstall() {
+		return
+	}
+	stats, err := dedupe(filepath.Dir(targetDir), targetDir)
+	if err != nil {
+		log.Printf("not deduplicating files of %v: %v", targetDir, err)
+		return
+	}
+	stats.log(filepath.Dir(targetDir))
+}
+
+// dedupeFlags parses the arguments of the dedupe command, args, which
+// takes no flags.
+func dedupeFlags(version string, args []string) {
+	fs := flag.NewFlagSet(version+" dedupe", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// dedupeStats reports the work done by dedupe.
+type dedupeStats struct {
+	files     int   // files replaced by links
+	reclaimed int64 // bytes of the replaced files
+	writable  int   // files not replaced, as they could only be hard linked
+}
+
+func (s dedupeStats) log(sdkDir string) {
+	if s.writable > 0 {
+		log.Printf("Not sharing %d duplicate files in %v: without reflinks, only files of read-only SDKs are shared", s.writable, sdkDir)
+	}
+	if s.files == 0 {
+		log.Printf("No duplicate files to share in %v", sdkDir)
+		return
+	}
+	log.Printf("Shared %d duplicate files in %v, reclaiming %s", s.files, sdkDir, fmtSize(s.reclaimed))
+}
+
+// dedupe replaces identical regular files of the SDKs installed in sdkDir
+// with reflinks to a single copy or, on filesystems without reflinks, hard
+// links to it. If only is set, only the files of the SDK in that directory
+// are replaced.
+//
+// Files are identified by the SHA-256 and permissions recorded in the
+// manifests of the SDKs, and only replaced if both the file and its copy
+// still match them, so modified files are left alone. Reflinks share
+// blocks until a file is written; hard links share the file itself, so
+// that writing it would change every SDK sharing it. Hard links are thus
+// only made between SDKs that are read-only, as installed SDKs are unless
+// GODL_READONLY=0.
+func dedupe(sdkDir, only string) (dedupeStats, error) {
+	var stats dedupeStats
+	roots, manifests, err := installedManifests(sdkDir)
+	if err != nil {
+		return stats, err
+	}
+	// Only look for copies of the files of only, which are replaced after
+	// those of the other SDKs are seen.
+	var want map[manifestEntry]bool
+	if only != "" {
+		m := manifests[only]
+		if m == nil {
+			return stats, fmt.Errorf("%s has no manifest", only)
+		}
+		want = make(map[manifestEntry]bool)
+		for _, e := range m.files {
+			want[e] = true
+		}
+		sort.SliceStable(roots, func(i, j int) bool { return roots[j] == only && roots[i] != only })
+	}
+
+	readOnly := make(map[string]bool)
+	for _, root := range roots {
+		readOnly[root] = isReadOnly(root)
+	}
+	type original struct{ file, root string }
+	copies := make(map[manifestEntry]original)
+	for _, root := range roots {
+		m := manifests[root]
+		rels := make([]string, 0, len(m.files))
+		for rel, e := range m.files {
+			if e.mode.IsRegular() {
+				rels = append(rels, rel)
+			}
+		}
+		sort.Strings(rels)
+		for _, rel := range rels {
+			e := m.files[rel]
+			file := filepath.Join(root, filepath.FromSlash(rel))
+			src, ok := copies[e]
+			if !ok {
+				if (want == nil || want[e]) && fileMatches(file, e) {
+					copies[e] = original{file, root}
+				}
+				continue
+			}
+			if only != "" && root != only {
+				continue
+			}
+			n, err := shareFile(src.file, file, e, readOnly[src.root] && readOnly[root])
+			if err == errWritableLink {
+				stats.writable++
+				continue
+			}
+			if err != nil {
+				return stats, err
+			}
+			if n > 0 {
+				stats.files++
+				stats.reclaimed += n
+			}
+		}
+	}
+	return stats, nil
+}
+
+// installedManifests returns the directories of the SDKs installed in
+// sdkDir that have manifests, in order, and their manifests.
+func installedManifests(sdkDir string) ([]string, map[string]*manifest, error) {
+	entries, err := os.ReadDir(sdkDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var roots []string
+	manifests := make(map[string]*manifest)
+	for _, e := range entries {
+		root := filepath.Join(sdkDir, e.Name())
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, unpackedOkay)); err != nil {
+			continue
+		}
+		m, err := readManifest(root)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("skipping %v: %v", root, err)
+			}
+			continue
+		}
+		roots = append(roots, root)
+		manifests[root] = m
+	}
+	return roots, manifests, nil
+}
+
+// fileMatches reports whether file is a regular file that matches the
+// manifest entry e.
+func fileMatches(file string, e manifestEntry) bool {
+	fi, err := os.Lstat(file)
+	if err != nil {
+		return false
+	}
+	got, err := hashEntry(file, fs.FileInfoToDirEntry(fi))
+	return err == nil && got == e
+}
+
+// errWritableLink is returned by shareFile for a file that could only be
+// shared by a hard link, which isn't allowed.
+var errWritableLink = errors.New("hard link to writable file")
+
+// shareFile replaces file, whose manifest entry is e, with a reflink or,
+// if link is set, a hard link to src, which matches e, unless the two
+// already share their contents or file no longer matches e. It returns the
+// size of file if it was replaced.
+func shareFile(src, file string, e manifestEntry, link bool) (int64, error) {
+	sfi, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	fi, err := os.Lstat(file)
+	if err != nil || os.SameFile(sfi, fi) || fi.Size() == 0 || sharesBlocks(src, file) {
+		return 0, nil
+	}
+	if !fileMatches(file, e) {
+		return 0, nil
+	}
+	// The directory of a read-only SDK is made writable just long enough
+	// to replace the file.
+	err = withWritableDir(filepath.Dir(file), func() error {
+		tmp := file + ".dedupe"
+		os.Remove(tmp)
+		if err := cloneFile(src, tmp, e.mode); err == nil {
+			os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+		} else if !link {
+			return errWritableLink
+		} else if err := os.Link(src, tmp); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, file); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
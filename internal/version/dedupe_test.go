@@ -0,0 +1,215 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// makeTestSDK creates an installed SDK with the given files and a manifest
+// of them in sdkDir/name.
+func makeTestSDK(t *testing.T, sdkDir, name string, files map[string]string) string {
+	t.Helper()
+	root := filepath.Join(sdkDir, name)
+	for rel, data := range files {
+		file := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := treeManifest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.write(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, unpackedOkay), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// makeTestSDKReadOnly makes the SDK in root read-only, as installed SDKs
+// are by default. root must be in a directory returned by tempSDKDir.
+func makeTestSDKReadOnly(t *testing.T, root string) {
+	t.Helper()
+	if err := makeReadOnly(root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkShared checks whether the files a and b share their contents.
+func checkShared(t *testing.T, a, b string, want bool) {
+	t.Helper()
+	afi, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bfi, err := os.Stat(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := os.SameFile(afi, bfi) || sharesBlocks(a, b); got != want {
+		t.Errorf("%s and %s shared = %v; want %v", a, b, got, want)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	sdkDir := tempSDKDir(t)
+	a := makeTestSDK(t, sdkDir, "go1.98", map[string]string{
+		"VERSION":          "go1.98",
+		"src/fmt/print.go": "package fmt\n",
+		"src/fmt/scan.go":  "package fmt // scan\n",
+	})
+	b := makeTestSDK(t, sdkDir, "go1.99", map[string]string{
+		"VERSION":          "go1.99",
+		"src/fmt/print.go": "package fmt\n",
+		"src/fmt/scan.go":  "package fmt // scan\n",
+	})
+	// A modified file isn't shared.
+	if err := os.WriteFile(filepath.Join(b, "src", "fmt", "scan.go"), []byte("package fmt // edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	makeTestSDKReadOnly(t, a)
+	makeTestSDKReadOnly(t, b)
+
+	stats, err := dedupe(sdkDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (dedupeStats{files: 1, reclaimed: int64(len("package fmt\n"))}); stats != want {
+		t.Errorf("dedupe = %+v; want %+v", stats, want)
+	}
+	checkShared(t, filepath.Join(a, "src", "fmt", "print.go"), filepath.Join(b, "src", "fmt", "print.go"), true)
+	checkShared(t, filepath.Join(a, "src", "fmt", "scan.go"), filepath.Join(b, "src", "fmt", "scan.go"), false)
+	if got, err := os.ReadFile(filepath.Join(b, "src", "fmt", "scan.go")); err != nil || string(got) != "package fmt // edited\n" {
+		t.Errorf("modified file = %q, %v", got, err)
+	}
+
+	// The shared files still verify.
+	for _, root := range []string{a, b} {
+		m, err := readManifest(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := verifyGoroot(root, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.missing) != 0 || len(r.extra) != 0 {
+			t.Errorf("verifying %s after dedupe reported %+v", root, r)
+		}
+	}
+
+	// Shared files are not shared again.
+	if stats, err := dedupe(sdkDir, ""); err != nil || stats.files != 0 {
+		t.Errorf("second dedupe = %+v, %v; want no files", stats, err)
+	}
+}
+
+func TestDedupeOnly(t *testing.T) {
+	sdkDir := tempSDKDir(t)
+	files := map[string]string{"src/fmt/print.go": "package fmt\n"}
+	a := makeTestSDK(t, sdkDir, "go1.97", files)
+	b := makeTestSDK(t, sdkDir, "go1.98", files)
+	c := makeTestSDK(t, sdkDir, "go1.99", files)
+	for _, root := range []string{a, b, c} {
+		makeTestSDKReadOnly(t, root)
+	}
+	stats, err := dedupe(sdkDir, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.files != 1 {
+		t.Errorf("dedupe = %+v; want 1 file", stats)
+	}
+	// The files of the other SDKs are left alone.
+	checkShared(t, filepath.Join(a, "src", "fmt", "print.go"), filepath.Join(b, "src", "fmt", "print.go"), true)
+	checkShared(t, filepath.Join(b, "src", "fmt", "print.go"), filepath.Join(c, "src", "fmt", "print.go"), false)
+}
+
+func TestDedupeWritable(t *testing.T) {
+	sdkDir := tempSDKDir(t)
+	files := map[string]string{"src/fmt/print.go": "package fmt\n"}
+	a := makeTestSDK(t, sdkDir, "go1.98", files)
+	b := makeTestSDK(t, sdkDir, "go1.99", files)
+	stats, err := dedupe(sdkDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The files of writable SDKs may only be shared by reflinks.
+	afi, err := os.Stat(filepath.Join(a, "src", "fmt", "print.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bfi, err := os.Stat(filepath.Join(b, "src", "fmt", "print.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.SameFile(afi, bfi) {
+		t.Errorf("dedupe hard linked the files of writable SDKs")
+	}
+	if stats.files+stats.writable != 1 {
+		t.Errorf("dedupe = %+v; want 1 file shared by reflink or left alone", stats)
+	}
+}
+
+func TestDedupeRemove(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("removing read-only files on Windows makes them writable")
+	}
+	sdkDir := tempSDKDir(t)
+	files := map[string]string{"src/fmt/print.go": "package fmt\n"}
+	a := makeTestSDK(t, sdkDir, "go1.98", files)
+	b := makeTestSDK(t, sdkDir, "go1.99", files)
+	makeTestSDKReadOnly(t, a)
+	makeTestSDKReadOnly(t, b)
+	if _, err := dedupe(sdkDir, ""); err != nil {
+		t.Fatal(err)
+	}
+	// Removing an SDK leaves the files it shared with another read-only.
+	if err := removeAll(a); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(b, "src", "fmt", "print.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&0222 != 0 {
+		t.Errorf("removing a deduplicated SDK left print.go of another with mode %v", fi.Mode())
+	}
+	m, err := readManifest(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := verifyGoroot(b, m); err != nil || !r.ok() {
+		t.Errorf("verifying SDK after removing another = %+v, %v", r, err)
+	}
+}
+
+func TestInstallDedupe(t *testing.T) {
+	t.Setenv("GODL_DEDUPE", "1")
+	sdkDir := tempSDKDir(t)
+	old := makeTestSDK(t, sdkDir, "go1.98", map[string]string{"src/fmt/print.go": "package fmt\n"})
+	makeTestSDKReadOnly(t, old)
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99", "src/fmt/print.go": "package fmt\n"})
+	mirror := httptest.NewServer(serveArchive(archive, true))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	root := filepath.Join(sdkDir, "go1.99")
+	if err := install(root, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	checkShared(t, filepath.Join(old, "src", "fmt", "print.go"), filepath.Join(root, "src", "fmt", "print.go"), true)
+}
@@ -150,6 +150,12 @@ func installTip(root, target string) error {
 		return fmt.Errorf("failed to cleanup git repository: %v", err)
 	}
 
+	return runMake(root, os.Environ())
+}
+
+// runMake builds the Go source tree in root with its make script, in the
+// environment env.
+func runMake(root string, env []string) error {
 	cmd := exec.Command(filepath.Join(root, "src", makeScript()))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -160,7 +166,7 @@ func installTip(root, target string) error {
 	if p := os.Getenv("PATH"); p != "" {
 		newPath += string(filepath.ListSeparator) + p
 	}
-	cmd.Env = dedupEnv(caseInsensitiveEnv, append(os.Environ(), "PATH="+newPath, "PWD="+cmd.Dir))
+	cmd.Env = dedupEnv(caseInsensitiveEnv, append(env, "PATH="+newPath, "PWD="+cmd.Dir))
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to build go: %v", err)
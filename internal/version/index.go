@@ -0,0 +1,149 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// releaseIndexURL is the URL of the index of all Go releases and their
+// files.
+var releaseIndexURL = "https://go.dev/dl/?mode=json&include=all"
+
+// releaseIndexFile is the name of the cached copy of the release index in
+// cacheDir.
+const releaseIndexFile = "releases.json"
+
+// Errors reported when the release index disagrees with the mirror.
+var (
+	errIndexSize     = errors.New("archive size doesn't match release index")
+	errIndexChecksum = errors.New(".sha256 file doesn't match release index")
+	errIndexArchive  = errors.New("archive doesn't match release index")
+)
+
+// A release is an entry of the release index.
+type release struct {
+	Version string        `json:"version"`
+	Files   []releaseFile `json:"files"`
+}
+
+// A releaseFile is a file of a release in the release index.
+type releaseFile struct {
+	Filename string `json:"filename"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Version  string `json:"version"`
+	SHA256   string `json:"sha256"`
+	Size     int64  `json:"size"`
+	Kind     string `json:"kind"`
+}
+
+// verifyWithIndex reports whether archives are verified against the
+// release index rather than the .sha256 file next to them, as selected by
+// GODL_VERIFY=index.
+func verifyWithIndex() bool {
+	return os.Getenv("GODL_VERIFY") == "index"
+}
+
+// cacheDir returns the directory in which to cache downloads: $GODL_CACHE
+// if set, or a directory in the user cache directory.
+func cacheDir() (string, error) {
+	if dir := os.Getenv("GODL_CACHE"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "golang-dl"), nil
+}
+
+// lookupReleaseIndex returns the entry of the release index for the file
+// with the given name, or nil if the index doesn't list it. Releases never
+// change, so a cached copy of the index is used if it lists the file.
+// Otherwise the index is downloaded again, falling back to the cached copy
+// if that fails.
+func lookupReleaseIndex(filename string) (*releaseFile, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	cached := filepath.Join(dir, releaseIndexFile)
+	data, cacheErr := os.ReadFile(cached)
+	if cacheErr == nil {
+		if f, err := findReleaseFile(data, filename); err == nil && f != nil {
+			return f, nil
+		}
+	}
+
+	index, fetchErr := slurpURLToString(releaseIndexURL)
+	if fetchErr == nil {
+		data = []byte(index)
+		if err := writeFileAtomic(cached, data); err != nil {
+			log.Printf("caching release index: %v", err)
+		}
+	} else if cacheErr != nil {
+		return nil, fmt.Errorf("fetching release index: %v", fetchErr)
+	} else {
+		log.Printf("fetching release index: %v; using cached copy", fetchErr)
+	}
+	return findReleaseFile(data, filename)
+}
+
+// findReleaseFile returns the entry for filename in the release index data,
+// or nil if there is none.
+func findReleaseFile(data []byte, filename string) (*releaseFile, error) {
+	var releases []release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("parsing release index: %v", err)
+	}
+	for _, r := range releases {
+		for i := range r.Files {
+			if r.Files[i].Filename == filename {
+				return &r.Files[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// expectedSHA256 returns the SHA-256 that the archive at goURL must have.
+// If indexed is not nil, it is the release index entry for the archive,
+// which takes precedence over the .sha256 file; the latter is then only
+// used to cross-check the index.
+func expectedSHA256(goURL string, indexed *releaseFile) (string, error) {
+	sumURL := checksumURL(goURL)
+	sha, err := slurpURLToString(sumURL)
+	sha = strings.TrimSpace(sha)
+	if indexed == nil {
+		return sha, err
+	}
+	if err != nil {
+		log.Printf("not cross-checking release index with .sha256 file: %v", err)
+	} else if sha != indexed.SHA256 {
+		return "", fmt.Errorf("%w: %s has %s; index has %s", errIndexChecksum, sumURL, sha, indexed.SHA256)
+	}
+	return indexed.SHA256, nil
+}
+
+// writeFileAtomic writes data to the named file, replacing it only once
+// all of data has been written.
+func writeFileAtomic(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// serveReleaseIndex sets releaseIndexURL to a server listing a single file
+// for go1.99, and returns the server.
+func serveReleaseIndex(t *testing.T, f releaseFile) *httptest.Server {
+	t.Helper()
+	data, err := json.Marshal([]release{{Version: "go1.99", Files: []releaseFile{f}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	t.Cleanup(ts.Close)
+	old := releaseIndexURL
+	releaseIndexURL = ts.URL + "/dl/?mode=json&include=all"
+	t.Cleanup(func() { releaseIndexURL = old })
+	return ts
+}
+
+func TestInstallReleaseIndex(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	good := releaseFile{
+		Filename: versionArchiveName("go1.99"),
+		SHA256:   fmt.Sprintf("%x", sha256.Sum256(archive)),
+		Size:     int64(len(archive)),
+	}
+	t.Setenv("GODL_VERIFY", "index")
+	t.Setenv("GODL_CACHE", t.TempDir())
+
+	tests := []struct {
+		name     string
+		index    releaseFile
+		checksum bool
+		wantErr  error
+	}{
+		{"size", releaseFile{Filename: good.Filename, SHA256: good.SHA256, Size: good.Size + 1}, true, errIndexSize},
+		{"checksum", releaseFile{Filename: good.Filename, SHA256: fmt.Sprintf("%x", sha256.Sum256(nil)), Size: good.Size}, true, errIndexChecksum},
+		{"archive", releaseFile{Filename: good.Filename, SHA256: fmt.Sprintf("%x", sha256.Sum256(nil)), Size: good.Size}, false, errIndexArchive},
+		{"good", good, true, nil},
+		{"good without .sha256", good, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GODL_CACHE", t.TempDir())
+			serveReleaseIndex(t, tt.index)
+			mirror := httptest.NewServer(serveArchive(archive, tt.checksum))
+			defer mirror.Close()
+			t.Setenv("GODL_MIRRORS", mirror.URL)
+
+			err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("install = %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallReleaseIndexOffline(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	t.Setenv("GODL_VERIFY", "index")
+	cache := t.TempDir()
+	t.Setenv("GODL_CACHE", cache)
+	index := serveReleaseIndex(t, releaseFile{
+		Filename: versionArchiveName("go1.99"),
+		SHA256:   fmt.Sprintf("%x", sha256.Sum256(archive)),
+		Size:     int64(len(archive)),
+	})
+	mirror := httptest.NewServer(serveArchive(archive, false))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	if err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cache, releaseIndexFile)); err != nil {
+		t.Fatalf("release index not cached: %v", err)
+	}
+	index.Close()
+	if err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform()); err != nil {
+		t.Fatalf("install with cached release index: %v", err)
+	}
+}
+
+func TestInstallReleaseIndexMirrorFallback(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	t.Setenv("GODL_VERIFY", "index")
+	t.Setenv("GODL_CACHE", t.TempDir())
+	serveReleaseIndex(t, releaseFile{
+		Filename: versionArchiveName("go1.99"),
+		SHA256:   fmt.Sprintf("%x", sha256.Sum256(archive)),
+		Size:     int64(len(archive)),
+	})
+	empty := httptest.NewServer(http.NotFoundHandler())
+	defer empty.Close()
+	mirror := httptest.NewServer(serveArchive(archive, false))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", empty.URL+","+mirror.URL)
+
+	if err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+}
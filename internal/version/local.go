@@ -0,0 +1,151 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// installLocal installs a version of Go from the release archive in the
+// local file archive, such as one copied onto an offline machine, to the
+// directory of the platform named by the archive for the version in root,
+// as returned by goroot. The archive is verified like a downloaded one,
+// against the .sha256 file next to it unless $GODL_CHECKSUM_URL is set.
+func installLocal(root, version, archive string) error {
+	base := filepath.Base(archive)
+	p, ok := archivePlatform(version, base)
+	if !ok {
+		return fmt.Errorf("%s is not a release archive of %s", archive, version)
+	}
+	targetDir := p.sdkDir(root)
+	if _, err := os.Stat(filepath.Join(targetDir, unpackedOkay)); err == nil {
+		log.Printf("%s: already downloaded in %v", version, targetDir)
+		return nil
+	}
+	got, err := archiveVersion(archive)
+	if err != nil {
+		return err
+	}
+	if got != version {
+		return fmt.Errorf("%s holds %s, not %s", archive, got, version)
+	}
+	goURL, err := fileURL(archive)
+	if err != nil {
+		return err
+	}
+	err = installStaged(targetDir, func(dir string) error {
+		err := fetchArchive(dir, version, p, goURL)
+		var noRelease *noReleaseError
+		if errors.As(err, &noRelease) {
+			return fmt.Errorf("no SHA-256 to verify %s against at %s; copy its .sha256 file next to it, or set GODL_VERIFY=index", archive, noRelease.url)
+		}
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, unpackedOkay), nil, 0644)
+	})
+	if err != nil {
+		return err
+	}
+	dedupeInstalled(targetDir)
+	logInstalled(targetDir, version, p)
+	return nil
+}
+
+// archivePlatform returns the platform of the release archive of version
+// with the given file name, such as "go1.22.3.linux-armv6l.tar.gz".
+func archivePlatform(version, name string) (p platform, ok bool) {
+	ext := archiveExt(name)
+	if ext == "" || !strings.HasPrefix(name, version+".") {
+		return p, false
+	}
+	osArch := strings.TrimSuffix(strings.TrimPrefix(name, version+"."), ext)
+	p.goos, p.goarch, ok = strings.Cut(osArch, "-")
+	if !ok || p.goos == "" || p.goarch == "" || strings.Contains(osArch, ".") {
+		return p, false
+	}
+	if p.goarch == "armv6l" {
+		p.goarch = "arm"
+	}
+	want := p.archiveName(version)
+	if ext == ".tar.xz" || ext == ".tar.zst" {
+		// A tar.gz archive recompressed by a mirror.
+		want = strings.TrimSuffix(want, ".tar.gz") + ext
+	}
+	return p, name == want
+}
+
+// archiveVersion returns the Go version that the release archive in file
+// holds, from the first line of its go/VERSION file.
+func archiveVersion(file string) (string, error) {
+	format, err := formatOfFile(file)
+	if err != nil {
+		return "", err
+	}
+	if err := format.checkCommand(); err != nil {
+		return "", err
+	}
+	var r io.Reader
+	if format.decompress == nil {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			return "", err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.Name == "go/VERSION" {
+				rc, err := f.Open()
+				if err != nil {
+					return "", err
+				}
+				defer rc.Close()
+				r = rc
+				break
+			}
+		}
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		zr, err := decompress(f)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %v", file, err)
+		}
+		defer zr.Close()
+		tr := tar.NewReader(zr)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("reading %s: %v", file, err)
+			}
+			if h.Name == "go/VERSION" {
+				r = tr
+				break
+			}
+		}
+	}
+	if r == nil {
+		return "", fmt.Errorf("%s has no go/VERSION file", file)
+	}
+	line, err := bufio.NewReader(io.LimitReader(r, 1<<10)).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("reading go/VERSION in %s: %v", file, err)
+	}
+	return strings.TrimSpace(line), nil
+}
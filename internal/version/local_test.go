@@ -0,0 +1,121 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLocalArchive writes archive as the release archive of go1.99 for
+// the host to dir, with its .sha256 file unless checksum is false, and
+// returns its file name.
+func writeLocalArchive(t *testing.T, dir string, archive []byte, checksum bool) string {
+	t.Helper()
+	file := filepath.Join(dir, versionArchiveName("go1.99"))
+	if err := os.WriteFile(file, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if checksum {
+		sum := fmt.Sprintf("%x\n", sha256.Sum256(archive))
+		if err := os.WriteFile(file+".sha256", []byte(sum), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return file
+}
+
+func TestInstallLocal(t *testing.T) {
+	t.Setenv("GODL_MIRRORS", "http://mirror.invalid/")
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99\ntime 2026-10-17T00:00:00Z\n"})
+	file := writeLocalArchive(t, t.TempDir(), archive, true)
+
+	root := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := installLocal(root, "go1.99", file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, unpackedOkay)); err != nil {
+		t.Error(err)
+	}
+
+	err := installLocal(root, "go1.98", file)
+	if err == nil || !strings.Contains(err.Error(), "not a release archive of go1.98") {
+		t.Errorf("installLocal of wrong version = %v; want not a release archive error", err)
+	}
+}
+
+func TestInstallLocalMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		checksum bool
+		want     string
+	}{
+		{"wrong version", "go1.98", true, "holds go1.98, not go1.99"},
+		{"no checksum", "go1.99", false, "no SHA-256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := testArchive(t, map[string]string{"VERSION": tt.version})
+			file := writeLocalArchive(t, t.TempDir(), archive, tt.checksum)
+			root := filepath.Join(tempSDKDir(t), "go1.99")
+			err := installLocal(root, "go1.99", file)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("installLocal = %v; want %q", err, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(root, unpackedOkay)); err == nil {
+				t.Errorf("failed install marked as installed")
+			}
+		})
+	}
+}
+
+func TestInstallFileMirror(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	dir := t.TempDir()
+	writeLocalArchive(t, dir, archive, true)
+	mirror, err := fileURL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GODL_MIRRORS", mirror)
+
+	root := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(root, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(root, "VERSION")); err != nil || string(got) != "go1.99" {
+		t.Errorf("VERSION = %q, %v; want %q", got, err, "go1.99")
+	}
+}
+
+func TestArchivePlatform(t *testing.T) {
+	tests := []struct {
+		name string
+		want platform
+		ok   bool
+	}{
+		{"go1.99.linux-amd64.tar.gz", platform{"linux", "amd64"}, true},
+		{"go1.99.linux-armv6l.tar.gz", platform{"linux", "arm"}, true},
+		{"go1.99.windows-386.zip", platform{"windows", "386"}, true},
+		{"go1.99.windows-386.tar.gz", platform{}, false},
+		{"go1.99.linux-amd64.tar.zst", platform{"linux", "amd64"}, true},
+		{"go1.99.darwin-arm64.tar.xz", platform{"darwin", "arm64"}, true},
+		{"go1.99.windows-386.tar.zst", platform{}, false},
+		{"go1.99.src.tar.gz", platform{}, false},
+		{"go1.98.linux-amd64.tar.gz", platform{}, false},
+		{"go1.99.1.linux-amd64.tar.gz", platform{}, false},
+	}
+	for _, tt := range tests {
+		p, ok := archivePlatform("go1.99", tt.name)
+		if ok != tt.ok || (ok && p != tt.want) {
+			t.Errorf("archivePlatform(%q) = %v, %v; want %v, %v", tt.name, p, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// manifestFile is the name of the file in an installed GOROOT that lists
+// the files installed in it, as written by manifest.write.
+const manifestFile = ".manifest"
+
+// A manifest lists the files of an installed GOROOT, by their
+// slash-separated paths relative to it, so that the GOROOT can later be
+// checked for files that were modified, removed or added. It also names
+// the verified archive the files were unpacked from, if any, so that they
+// can be restored from it.
+//
+// The methods adding files may be called concurrently, and do nothing on
+// a nil manifest.
+type manifest struct {
+	archive string // file name of the archive, such as "go1.22.3.linux-amd64.tar.gz"
+	sha256  string // hex-encoded SHA-256 of the archive
+
+	mu    sync.Mutex
+	files map[string]manifestEntry
+}
+
+// A manifestEntry records the hex-encoded SHA-256 and permissions of a
+// regular file, or the SHA-256 of the target of a symbolic link, whose
+// mode is then ModeSymlink.
+type manifestEntry struct {
+	sha256 string
+	mode   fs.FileMode
+}
+
+func newManifest(archive, sha256 string) *manifest {
+	return &manifest{archive: archive, sha256: sha256, files: make(map[string]manifestEntry)}
+}
+
+// addFile records the regular file rel.
+func (m *manifest) addFile(rel, sha256 string, perm fs.FileMode) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[rel] = manifestEntry{sha256, perm.Perm()}
+}
+
+// addSymlink records the symbolic link rel to target.
+func (m *manifest) addSymlink(rel, target string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[rel] = manifestEntry{fmt.Sprintf("%x", sha256.Sum256([]byte(target))), fs.ModeSymlink}
+}
+
+// addHardlink records rel as a hard link to the regular file target,
+// which must already be recorded.
+func (m *manifest) addHardlink(rel, target string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[rel] = m.files[target]
+}
+
+// chmod records that the permissions of the regular file rel, if it is
+// recorded, were changed to perm.
+func (m *manifest) chmod(rel string, perm fs.FileMode) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.files[rel]; ok && e.mode&fs.ModeSymlink == 0 {
+		e.mode = perm.Perm()
+		m.files[rel] = e
+	}
+}
+
+// write writes the manifest to the manifestFile in dir. Its first line
+// names the archive, and each other line lists the SHA-256, octal
+// permissions (or "symlink") and path of a file, sorted by path.
+func (m *manifest) write(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rels := make([]string, 0, len(m.files))
+	for rel := range m.files {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	var b strings.Builder
+	fmt.Fprintf(&b, "archive %s %s\n", m.archive, m.sha256)
+	for _, rel := range rels {
+		e := m.files[rel]
+		mode := fmt.Sprintf("%04o", e.mode.Perm())
+		if e.mode&fs.ModeSymlink != 0 {
+			mode = "symlink"
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", e.sha256, mode, rel)
+	}
+	return os.WriteFile(filepath.Join(dir, manifestFile), []byte(b.String()), 0644)
+}
+
+// readManifest reads the manifestFile in dir.
+func readManifest(dir string) (*manifest, error) {
+	file := filepath.Join(dir, manifestFile)
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	if !s.Scan() {
+		return nil, fmt.Errorf("%s is empty", file)
+	}
+	// The archive and its SHA-256 are empty if there is no archive.
+	header := strings.Split(s.Text(), " ")
+	if len(header) != 3 || header[0] != "archive" || header[1] != "" && !isManifestPath(header[1], false) {
+		return nil, fmt.Errorf("%s: malformed header %q", file, s.Text())
+	}
+	m := newManifest(header[1], header[2])
+	for line := 2; s.Scan(); line++ {
+		fields := strings.SplitN(s.Text(), " ", 3)
+		if len(fields) != 3 || !isManifestPath(fields[2], true) {
+			return nil, fmt.Errorf("%s:%d: malformed line %q", file, line, s.Text())
+		}
+		e := manifestEntry{sha256: fields[0], mode: fs.ModeSymlink}
+		if fields[1] != "symlink" {
+			perm, err := strconv.ParseUint(fields[1], 8, 32)
+			if err != nil || perm&^0777 != 0 {
+				return nil, fmt.Errorf("%s:%d: malformed mode %q", file, line, fields[1])
+			}
+			e.mode = fs.FileMode(perm)
+		}
+		m.files[fields[2]] = e
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", file, err)
+	}
+	return m, nil
+}
+
+// isManifestPath reports whether rel is a clean, slash-separated path
+// inside a GOROOT, as listed by a manifest: verify -repair removes and
+// replaces the files it names. If nested is false, rel must name a file
+// at the top level.
+func isManifestPath(rel string, nested bool) bool {
+	if rel == "" || strings.HasPrefix(rel, "/") || strings.Contains(rel, `\`) || filepath.VolumeName(filepath.FromSlash(rel)) != "" {
+		return false
+	}
+	for _, elem := range strings.Split(rel, "/") {
+		switch elem {
+		case "", ".", "..":
+			return false
+		}
+	}
+	return nested || !strings.Contains(rel, "/")
+}
+
+// treeManifest returns a manifest without an archive of the files in the
+// installed GOROOT root, such as one built from source.
+func treeManifest(root string) (*manifest, error) {
+	m := newManifest("", "")
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := filepath.ToSlash(strings.TrimPrefix(file, root+string(filepath.Separator)))
+		if isInstallFile(rel) {
+			return nil
+		}
+		e, err := hashEntry(file, d)
+		if err != nil {
+			return err
+		}
+		m.files[rel] = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// isInstallFile reports whether the file rel of an installed GOROOT was
+// written by the install rather than unpacked: the sentinel, the manifest
+// and the downloaded archive at the top level.
+func isInstallFile(rel string) bool {
+	if strings.Contains(rel, "/") {
+		return false
+	}
+	return rel == unpackedOkay || rel == manifestFile || isDownloadFile(rel)
+}
+
+// hashEntry returns the manifest entry of the file in a GOROOT, or an
+// error if it is neither a regular file nor a symbolic link.
+func hashEntry(file string, d fs.DirEntry) (manifestEntry, error) {
+	switch {
+	case d.Type()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(file)
+		if err != nil {
+			return manifestEntry{}, err
+		}
+		return manifestEntry{fmt.Sprintf("%x", sha256.Sum256([]byte(filepath.ToSlash(target)))), fs.ModeSymlink}, nil
+	case d.Type().IsRegular():
+		f, err := os.Open(file)
+		if err != nil {
+			return manifestEntry{}, err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return manifestEntry{}, err
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return manifestEntry{}, err
+		}
+		return manifestEntry{fmt.Sprintf("%x", h.Sum(nil)), fi.Mode().Perm()}, nil
+	}
+	return manifestEntry{}, fmt.Errorf("%s is not a regular file or symbolic link", file)
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultMirrorURL is the base URL of the official Go release archives.
+const defaultMirrorURL = "https://dl.google.com/go/"
+
+// mirrorURLs returns the base URLs to try, in order, to download release
+// archives. They are read from the comma-separated list in $GODL_MIRRORS,
+// and default to defaultMirrorURL. The list may contain proxyMirror.
+func mirrorURLs() []string {
+	urls := splitBaseURLs(os.Getenv("GODL_MIRRORS"))
+	if len(urls) == 0 {
+		return []string{defaultMirrorURL}
+	}
+	return urls
+}
+
+// checksumURL returns the URL of the .sha256 file for the archive at
+// archiveURL. It is fetched from the same mirror as the archive unless
+// $GODL_CHECKSUM_URL sets the base URL of a separate, trusted source.
+func checksumURL(archiveURL string) string {
+	if bases := splitBaseURLs(os.Getenv("GODL_CHECKSUM_URL")); len(bases) > 0 {
+		return bases[0] + path.Base(archiveURL) + ".sha256"
+	}
+	return archiveURL + ".sha256"
+}
+
+// splitBaseURLs splits a comma-separated list of base URLs, ensuring that
+// each of them ends in a slash.
+func splitBaseURLs(list string) []string {
+	var urls []string
+	for _, u := range strings.Split(list, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		if u == proxyMirror {
+			urls = append(urls, u)
+			continue
+		}
+		if !strings.HasSuffix(u, "/") {
+			u += "/"
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// tryNextMirror reports whether the failure of installFrom with err on one
+// mirror warrants trying the next one: the mirror doesn't have the archive,
+// couldn't be reached, or serves it in a form that can't be verified.
+func tryNextMirror(err error) bool {
+	var (
+		noRelease *noReleaseError
+		urlErr    *url.Error
+		netErr    net.Error
+	)
+	return errors.As(err, &noRelease) ||
+		errors.Is(err, errNoChecksum) ||
+		errors.As(err, &urlErr) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
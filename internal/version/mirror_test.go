@@ -0,0 +1,134 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testArchive returns a release archive for the current platform
+// containing the given files under the "go/" prefix.
+func testArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if strings.HasSuffix(versionArchiveName("go1.99"), ".zip") {
+		zw := zip.NewWriter(&buf)
+		for name, data := range files {
+			w, err := zw.Create("go/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(data))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, data := range files {
+		hdr := &tar.Header{Name: "go/" + name, Mode: 0644, Size: int64(len(data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(data))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// serveArchive returns a handler serving archive, and its .sha256 file
+// unless checksum is false, as the release archive of go1.99.
+func serveArchive(archive []byte, checksum bool) http.Handler {
+	name := versionArchiveName("go1.99")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/"+name):
+			w.Header().Set("ETag", `"go1.99"`)
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(archive))
+		case checksum && strings.HasSuffix(r.URL.Path, "/"+name+".sha256"):
+			fmt.Fprintf(w, "%x\n", sha256.Sum256(archive))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestInstallMirrorFallback(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	empty := httptest.NewServer(http.NotFoundHandler())
+	defer empty.Close()
+	mirror := httptest.NewServer(serveArchive(archive, true))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", empty.URL+"/go,"+mirror.URL+"/go/")
+
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "VERSION")); err != nil || string(got) != "go1.99" {
+		t.Errorf("VERSION = %q, %v; want %q", got, err, "go1.99")
+	}
+	if _, err := os.Stat(filepath.Join(dir, unpackedOkay)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInstallMirrorNoRelease(t *testing.T) {
+	empty := httptest.NewServer(http.NotFoundHandler())
+	defer empty.Close()
+	t.Setenv("GODL_MIRRORS", empty.URL+"/a/,"+empty.URL+"/b/")
+
+	err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform())
+	if err == nil || !strings.Contains(err.Error(), "no binary release") || !strings.Contains(err.Error(), empty.URL+"/b/") {
+		t.Errorf("install = %v; want no binary release error for the last mirror", err)
+	}
+}
+
+func TestInstallChecksumURL(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	mirror := httptest.NewServer(serveArchive(archive, false))
+	defer mirror.Close()
+	trusted := httptest.NewServer(serveArchive(nil, true))
+	defer trusted.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	// The mirror has no .sha256 files.
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err == nil {
+		t.Fatal("install succeeded without a checksum")
+	}
+
+	// The trusted source disagrees with the mirror.
+	t.Setenv("GODL_CHECKSUM_URL", trusted.URL)
+	if err := install(dir, "go1.99", hostPlatform()); err == nil || !strings.Contains(err.Error(), "SHA256") {
+		t.Fatalf("install = %v; want SHA256 mismatch", err)
+	}
+
+	good := httptest.NewServer(serveArchive(archive, true))
+	defer good.Close()
+	t.Setenv("GODL_CHECKSUM_URL", good.URL+"/checksums")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// minChunkSize is the smallest byte range worth fetching over its own
+// connection.
+const minChunkSize = 1 << 20
+
+// downloadConnections returns the number of connections to use to download
+// an archive, as set by $GODL_CONNECTIONS. A value of 1, the default,
+// downloads over a single connection.
+func downloadConnections() int64 {
+	s := os.Getenv("GODL_CONNECTIONS")
+	if s == "" {
+		return 1
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 1 {
+		log.Printf("ignoring invalid GODL_CONNECTIONS=%q", s)
+		return 1
+	}
+	return n
+}
+
+// copyFromURLParallel downloads srcURL, which is size bytes long, to dstFile
+// by splitting it into conns byte ranges and fetching them concurrently.
+// The validator, as returned by responseValidator, ensures that all ranges
+// come from the same version of the file.
+//
+// Unlike copyFromURL, an interrupted parallel download is not resumed.
+func copyFromURLParallel(dstFile, srcURL string, size int64, validator string, conns int64) (err error) {
+	partFile := dstFile + partialSuffix
+	discardPartialDownload(partFile, partFile+validatorSuffix)
+	f, err := os.Create(partFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(partFile)
+		}
+	}()
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pw := &lockedWriter{w: newProgressWriter(io.Discard, phaseDownloading, 0, size)}
+	chunk := (size + conns - 1) / conns
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for start := int64(0); start < size; start += chunk {
+		end := start + chunk
+		if end > size {
+			end = size
+		}
+		wg.Add(1)
+		go func(start, end int64) {
+			defer wg.Done()
+			what := fmt.Sprintf("downloading bytes %d-%d of %s", start, end-1, srcURL)
+			err := retry(what, func() error {
+				n, err := copyRange(ctx, f, pw, srcURL, validator, start, end)
+				start += n
+				return err
+			})
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	pw.w.done()
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partFile, dstFile)
+}
+
+// copyRange copies bytes [start, end) of srcURL to the same offsets in f,
+// and reports them to progress. It returns the number of bytes copied.
+func copyRange(ctx context.Context, f *os.File, progress io.Writer, srcURL, validator string, start, end int64) (int64, error) {
+	_, _, idle := downloadTimeouts()
+	ctx, stalls := watchStalls(ctx, idle)
+	defer stalls.stop()
+	req, err := http.NewRequestWithContext(ctx, "GET", srcURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+	req.Header.Set("If-Range", validator)
+	c, err := httpClient()
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("file changed on server during download")
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		return 0, newStatusError(res, fmt.Errorf("fetching bytes %d-%d: %v", start, end-1, res.Status))
+	}
+	if got, ok := contentRangeStart(res.Header.Get("Content-Range")); !ok || got != start {
+		return 0, fmt.Errorf("server returned range %q; want bytes from %d", res.Header.Get("Content-Range"), start)
+	}
+	w := io.MultiWriter(stalls, &offsetWriter{f: f, off: start}, progress)
+	stalls.start()
+	n, err := io.Copy(w, limitReader(io.LimitReader(res.Body, end-start), downloadLimiter()))
+	if err != nil {
+		return n, stalls.check(err, n, end-start)
+	}
+	if n != end-start {
+		return n, fmt.Errorf("copied %v bytes of range %d-%d; expected %v: %w", n, start, end-1, end-start, io.ErrUnexpectedEOF)
+	}
+	return n, nil
+}
+
+// offsetWriter writes to f sequentially, starting at offset off.
+type offsetWriter struct {
+	f   *os.File
+	off int64
+}
+
+func (w *offsetWriter) Write(buf []byte) (int, error) {
+	n, err := w.f.WriteAt(buf, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+// lockedWriter serializes writes to a progressWriter shared by concurrent
+// downloads.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  *progressWriter
+}
+
+func (w *lockedWriter) Write(buf []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(buf)
+}
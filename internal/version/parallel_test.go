@@ -0,0 +1,82 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCopyFromURLParallel(t *testing.T) {
+	content := make([]byte, 4*minChunkSize)
+	rand.New(rand.NewSource(1)).Read(content)
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "go.tar.gz")
+	if err := copyFromURLParallel(dst, ts.URL, int64(len(content)), `"v1"`, 4); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("reassembled download has wrong content")
+	}
+	sort.Strings(ranges)
+	want := []string{"bytes=0-1048575", "bytes=1048576-2097151", "bytes=2097152-3145727", "bytes=3145728-4194303"}
+	if len(ranges) != len(want) {
+		t.Fatalf("got requests for ranges %q; want %q", ranges, want)
+	}
+	for i := range want {
+		if ranges[i] != want[i] {
+			t.Errorf("got requests for ranges %q; want %q", ranges, want)
+			break
+		}
+	}
+}
+
+func TestCopyFromURLParallelChanged(t *testing.T) {
+	content := make([]byte, 2*minChunkSize)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "go.tar.gz")
+	start := time.Now()
+	if err := copyFromURLParallel(dst, ts.URL, int64(len(content)), `"v1"`, 2); err == nil {
+		t.Fatal("copyFromURLParallel succeeded with a stale validator")
+	}
+	// The other range is abandoned, not retried.
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("copyFromURLParallel failed after %v", d)
+	}
+	for _, f := range []string{dst, dst + partialSuffix} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("failed download left %s behind", f)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import "runtime"
+
+// A platform is an operating system and architecture for which Go is
+// released, named by their GOOS and GOARCH values.
+type platform struct {
+	goos, goarch string
+}
+
+// hostPlatform returns the platform this program runs on.
+func hostPlatform() platform {
+	return platform{getOS(), runtime.GOARCH}
+}
+
+func (p platform) String() string {
+	return p.goos + "/" + p.goarch
+}
+
+// archiveArch returns the name of the architecture in the names of release
+// archives, which differs from GOARCH for 32-bit ARM on Linux.
+func (p platform) archiveArch() string {
+	if p.goos == "linux" && p.goarch == "arm" {
+		return "armv6l"
+	}
+	return p.goarch
+}
+
+// archiveName returns the file name of the zip or tar.gz archive of the
+// given Go version for the platform.
+func (p platform) archiveName(version string) string {
+	ext := ".tar.gz"
+	if p.goos == "windows" {
+		ext = ".zip"
+	}
+	return version + "." + p.goos + "-" + p.archiveArch() + ext
+}
+
+// sdkDir returns the directory in which to install the given Go version
+// for the platform: root, the directory of the version for the host, if
+// the platform is the host, or a sibling of it qualified by the platform,
+// such as "go1.22.3.linux-arm64", otherwise. Run only ever executes the go
+// command in root, so Go for another platform is never run on the host.
+func (p platform) sdkDir(root string) string {
+	if p == hostPlatform() {
+		return root
+	}
+	return root + "." + p.goos + "-" + p.archiveArch()
+}
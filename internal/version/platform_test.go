@@ -0,0 +1,80 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlatformArchiveName(t *testing.T) {
+	tests := []struct {
+		p    platform
+		want string
+	}{
+		{platform{"linux", "amd64"}, "go1.22.3.linux-amd64.tar.gz"},
+		{platform{"linux", "arm"}, "go1.22.3.linux-armv6l.tar.gz"},
+		{platform{"freebsd", "arm"}, "go1.22.3.freebsd-arm.tar.gz"},
+		{platform{"windows", "arm64"}, "go1.22.3.windows-arm64.zip"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.archiveName("go1.22.3"); got != tt.want {
+			t.Errorf("%v: archiveName = %q; want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadFlags(t *testing.T) {
+	root := filepath.Join("sdk", "go1.22.3")
+	host := hostPlatform()
+	if p := downloadFlags("go1.22.3", nil).platform; p != host || p.sdkDir(root) != root {
+		t.Errorf("default platform = %v in %s; want %v in %s", p, p.sdkDir(root), host, root)
+	}
+
+	t.Setenv("GODL_GOOS", "linux")
+	t.Setenv("GODL_GOARCH", "riscv64")
+	p := downloadFlags("go1.22.3", []string{"-arch=armv6l"}).platform
+	if want := (platform{"linux", "arm"}); p != want {
+		t.Errorf("platform = %v; want %v", p, want)
+	}
+	if p != host {
+		if got, want := p.sdkDir(root), root+".linux-armv6l"; got != want {
+			t.Errorf("sdkDir = %s; want %s", got, want)
+		}
+	}
+}
+
+func TestInstallOtherPlatform(t *testing.T) {
+	if strings.HasSuffix(versionArchiveName("go1.99"), ".zip") {
+		t.Skip("test archive is a zip file")
+	}
+	p := platform{"plan9", "arm"}
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	name := p.archiveName("go1.99")
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Serve the host archive under the name of that for p.
+		r.URL.Path = strings.Replace(r.URL.Path, name, versionArchiveName("go1.99"), 1)
+		serveArchive(archive, true).ServeHTTP(w, r)
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	dir := p.sdkDir(filepath.Join(tempSDKDir(t), "go1.99"))
+	if err := install(dir, "go1.99", p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, unpackedOkay)); err != nil {
+		t.Error(err)
+	}
+
+	err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", platform{"plan9", "mips"})
+	if want := "no binary release of go1.99 for plan9/mips"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("install = %v; want %q", err, want)
+	}
+}
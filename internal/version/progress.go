@@ -0,0 +1,203 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Phases of install reported by a progressRenderer.
+const (
+	phaseDownloading = "downloading"
+	phaseVerifying   = "verifying"
+	phaseUnpacking   = "unpacking"
+)
+
+// progressState is the state of a phase of install.
+type progressState struct {
+	phase   string
+	n       int64         // bytes processed
+	total   int64         // total bytes, or -1 if unknown
+	rate    float64       // bytes per second, or -1 if unknown
+	elapsed time.Duration // since the phase started
+}
+
+// A progressRenderer reports the progress of install.
+type progressRenderer interface {
+	// update reports the progress of a phase.
+	update(s progressState)
+	// done reports that a phase is complete.
+	done(s progressState)
+}
+
+// newProgressRenderer returns the progress renderer selected by
+// $GODL_PROGRESS:
+//
+//   - "bar" redraws a progress bar with speed and ETA in place,
+//   - "plain" prints a line of progress every second,
+//   - "quiet" prints a summary line at the end of each phase,
+//   - "json" prints a JSON object per update to standard output.
+//
+// By default, the bar is used if standard error is a terminal, and quiet
+// otherwise.
+func newProgressRenderer() progressRenderer {
+	switch mode := os.Getenv("GODL_PROGRESS"); mode {
+	case "bar":
+		return &barRenderer{output: os.Stderr}
+	case "plain":
+		return &plainRenderer{output: os.Stderr}
+	case "quiet":
+		return &quietRenderer{output: os.Stderr}
+	case "json":
+		return &jsonRenderer{output: os.Stdout}
+	default:
+		if mode != "" && mode != "auto" {
+			log.Printf("ignoring invalid GODL_PROGRESS=%q", mode)
+		}
+		if isTerminal(os.Stderr) {
+			return &barRenderer{output: os.Stderr}
+		}
+		return &quietRenderer{output: os.Stderr}
+	}
+}
+
+// isTerminal reports whether f is a terminal able to redraw a line.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+}
+
+// phaseVerb returns the past tense of phase, to start progress lines with.
+func phaseVerb(phase string) string {
+	switch phase {
+	case phaseVerifying:
+		return "Verified"
+	case phaseUnpacking:
+		return "Unpacked"
+	default:
+		return "Downloaded"
+	}
+}
+
+// plainRenderer prints a line of progress at every update.
+type plainRenderer struct {
+	output    io.Writer
+	formatted bool // print sizes in human-readable units rather than bytes
+}
+
+func (r *plainRenderer) update(s progressState) {
+	end := " ..."
+	if s.n == s.total {
+		end = ""
+	}
+	if s.rate >= 0 {
+		end = " at " + fmtSize(int64(s.rate)) + "/s" + end
+	}
+	if r.formatted {
+		fmt.Fprintf(r.output, "%s %5.1f%% (%s / %s)%s\n",
+			phaseVerb(s.phase), (100.0*float64(s.n))/float64(s.total),
+			fmtSize(s.n), fmtSize(s.total), end)
+	} else {
+		fmt.Fprintf(r.output, "%s %5.1f%% (%*d / %d bytes)%s\n",
+			phaseVerb(s.phase), (100.0*float64(s.n))/float64(s.total),
+			ndigits(s.total), s.n, s.total, end)
+	}
+}
+
+func (r *plainRenderer) done(s progressState) { r.update(s) }
+
+// quietRenderer only prints a summary of each phase once it is done.
+type quietRenderer struct {
+	output io.Writer
+}
+
+func (r *quietRenderer) update(s progressState) {}
+
+func (r *quietRenderer) done(s progressState) {
+	fmt.Fprintf(r.output, "%s %s in %v", phaseVerb(s.phase), fmtSize(s.n), s.elapsed.Round(100*time.Millisecond))
+	if s.rate >= 0 {
+		fmt.Fprintf(r.output, " (%s/s)", fmtSize(int64(s.rate)))
+	}
+	fmt.Fprintln(r.output)
+}
+
+// barWidth is the width of the progress bar drawn by barRenderer.
+const barWidth = 30
+
+// barRenderer redraws a progress bar on the current terminal line.
+type barRenderer struct {
+	output io.Writer
+}
+
+func (r *barRenderer) update(s progressState) {
+	verb := strings.ToUpper(s.phase[:1]) + s.phase[1:]
+	if s.total <= 0 {
+		fmt.Fprintf(r.output, "\r%-12s %s%s\x1b[K", verb, fmtSize(s.n), r.rate(s))
+		return
+	}
+	filled := int(barWidth * s.n / s.total)
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	eta := ""
+	if s.rate > 0 && s.n < s.total {
+		eta = fmt.Sprintf("  ETA %v", (time.Duration(float64(s.total-s.n)/s.rate) * time.Second).Round(time.Second))
+	}
+	fmt.Fprintf(r.output, "\r%-12s [%s] %5.1f%%  %s / %s%s%s\x1b[K",
+		verb, bar, (100.0*float64(s.n))/float64(s.total),
+		fmtSize(s.n), fmtSize(s.total), r.rate(s), eta)
+}
+
+func (r *barRenderer) rate(s progressState) string {
+	if s.rate < 0 {
+		return ""
+	}
+	return "  " + fmtSize(int64(s.rate)) + "/s"
+}
+
+func (r *barRenderer) done(s progressState) {
+	r.update(s)
+	fmt.Fprintln(r.output)
+}
+
+// jsonRenderer prints each update as a line of JSON, for programs wrapping
+// the download command.
+type jsonRenderer struct {
+	output io.Writer
+}
+
+// jsonProgress is the JSON encoding of a progressState.
+type jsonProgress struct {
+	Phase string  `json:"phase"`
+	Bytes int64   `json:"bytes"`
+	Total int64   `json:"total"`
+	Rate  float64 `json:"rate"` // bytes per second, or -1 if unknown
+	Done  bool    `json:"done,omitempty"`
+}
+
+func (r *jsonRenderer) update(s progressState) { r.print(s, false) }
+
+func (r *jsonRenderer) done(s progressState) { r.print(s, true) }
+
+func (r *jsonRenderer) print(s progressState, done bool) {
+	rate := s.rate
+	if rate >= 0 {
+		rate = float64(int64(rate))
+	}
+	json.NewEncoder(r.output).Encode(jsonProgress{
+		Phase: s.phase,
+		Bytes: s.n,
+		Total: s.total,
+		Rate:  rate,
+		Done:  done,
+	})
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r := &jsonRenderer{output: &buf}
+	r.update(progressState{phase: phaseDownloading, n: 512, total: 1024, rate: 256.7})
+	r.done(progressState{phase: phaseUnpacking, n: 1024, total: 1024, rate: -1})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines of JSON; want 2:\n%s", len(lines), buf.String())
+	}
+	want := []jsonProgress{
+		{Phase: "downloading", Bytes: 512, Total: 1024, Rate: 256},
+		{Phase: "unpacking", Bytes: 1024, Total: 1024, Rate: -1, Done: true},
+	}
+	for i, line := range lines {
+		var got jsonProgress
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want[i] {
+			t.Errorf("line %d = %+v; want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestQuietRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r := &quietRenderer{output: &buf}
+	r.update(progressState{phase: phaseDownloading, n: 512, total: 2048, rate: 1024})
+	if buf.Len() != 0 {
+		t.Errorf("quiet renderer printed %q on update", buf.String())
+	}
+	r.done(progressState{phase: phaseDownloading, n: 2048, total: 2048, rate: 1024, elapsed: 2 * time.Second})
+	if got, want := buf.String(), "Downloaded 2 KB in 2s (1 KB/s)\n"; got != want {
+		t.Errorf("quiet renderer printed %q; want %q", got, want)
+	}
+}
+
+func TestBarRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r := &barRenderer{output: &buf}
+	r.update(progressState{phase: phaseDownloading, n: 512, total: 2048, rate: 512})
+	got := buf.String()
+	for _, want := range []string{"\rDownloading", "[=======>", " 25.0%", "512 B / 2 KB", "512 B/s", "ETA 3s", "\x1b[K"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("bar %q does not contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "\n") {
+		t.Errorf("bar %q ends the line before the phase is done", got)
+	}
+	r.done(progressState{phase: phaseDownloading, n: 2048, total: 2048, rate: 512})
+	if !strings.HasSuffix(buf.String(), "\n") {
+		t.Errorf("bar not ended when the phase is done")
+	}
+}
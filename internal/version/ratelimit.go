@@ -0,0 +1,136 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	limiterOnce sync.Once
+	limiter     *rateLimiter
+)
+
+// downloadLimiter returns the rate limiter shared by all downloads, as
+// configured by $GODL_MAX_RATE, or nil if downloads are not rate limited.
+func downloadLimiter() *rateLimiter {
+	limiterOnce.Do(func() {
+		s := os.Getenv("GODL_MAX_RATE")
+		if s == "" {
+			return
+		}
+		rate, err := parseRate(s)
+		if err != nil {
+			log.Printf("ignoring invalid GODL_MAX_RATE=%q: %v", s, err)
+			return
+		}
+		limiter = newRateLimiter(rate)
+	})
+	return limiter
+}
+
+// parseRate parses a rate in bytes per second such as "500KB/s" or "5M".
+// Units are powers of 1024, like those printed by fmtSize.
+func parseRate(s string) (float64, error) {
+	return parseSize(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+}
+
+// parseSize parses a positive number of bytes such as "500KB" or "1.5G".
+// Units are powers of 1024, like those printed by fmtSize.
+func parseSize(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	num := strings.TrimRight(s, "KMGBkmgib")
+	unit := strings.ToUpper(strings.TrimSpace(s[len(num):]))
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	switch unit {
+	case "":
+	case "K":
+		n *= 1 << 10
+	case "M":
+		n *= 1 << 20
+	case "G":
+		n *= 1 << 30
+	default:
+		return 0, fmt.Errorf("invalid unit in %q", s)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%q must be positive", s)
+	}
+	return n, nil
+}
+
+// A rateLimiter is a token bucket limiting the rate at which bytes are
+// read. The bucket holds up to one second worth of tokens.
+type rateLimiter struct {
+	rate float64 // bytes per second
+
+	mu     sync.Mutex
+	tokens float64 // may be negative, when readers are waiting
+	last   time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{rate: rate, last: time.Now()}
+}
+
+// maxRead returns the largest read worth issuing at once.
+func (l *rateLimiter) maxRead() int {
+	if l.rate < 512 {
+		return 512
+	}
+	return int(l.rate)
+}
+
+// wait takes n tokens from the bucket, sleeping until they are available.
+func (l *rateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	time.Sleep(d)
+}
+
+// limitReader returns a reader that reads from r no faster than the rate
+// allowed by l. If l is nil, it returns r.
+func limitReader(r io.Reader, l *rateLimiter) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{r: r, l: l}
+}
+
+type limitedReader struct {
+	r io.Reader
+	l *rateLimiter
+}
+
+func (r *limitedReader) Read(buf []byte) (int, error) {
+	if max := r.l.maxRead(); len(buf) > max {
+		buf = buf[:max]
+	}
+	n, err := r.r.Read(buf)
+	r.l.wait(n)
+	return n, err
+}
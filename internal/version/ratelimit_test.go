@@ -0,0 +1,51 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"100", 100},
+		{"100B/s", 100},
+		{"5MB/s", 5 << 20},
+		{"5 MB/s", 5 << 20},
+		{"1.5M", 1.5 * (1 << 20)},
+		{"512KiB/s", 512 << 10},
+		{"2g", 2 << 30},
+	}
+	for _, tt := range tests {
+		got, err := parseRate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseRate(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "fast", "5TB/s", "0", "-1MB/s"} {
+		if got, err := parseRate(in); err == nil {
+			t.Errorf("parseRate(%q) = %v; want error", in, got)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	const rate = 200 << 10
+	r := limitReader(bytes.NewReader(make([]byte, rate/2)), newRateLimiter(rate))
+	start := time.Now()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil || n != rate/2 {
+		t.Fatalf("io.Copy = %d, %v", n, err)
+	}
+	if d := time.Since(start); d < 400*time.Millisecond || d > 5*time.Second {
+		t.Errorf("reading half a second worth of bytes took %v", d)
+	}
+}
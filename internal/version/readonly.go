@@ -0,0 +1,131 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// readOnly reports whether to make installed SDKs read-only, like the
+// module cache, so that they aren't modified by accident.
+// GODL_READONLY=0 leaves them writable.
+func readOnly() bool {
+	return os.Getenv("GODL_READONLY") != "0"
+}
+
+// isReadOnly reports whether the installed SDK in root was made read-only.
+func isReadOnly(root string) bool {
+	fi, err := os.Stat(root)
+	return err == nil && fi.Mode().Perm()&0200 == 0
+}
+
+// makeReadOnly removes the write permissions of the files and directories
+// in root, and records the new permissions of the files in its manifest,
+// if it has one.
+func makeReadOnly(root string) error {
+	m, err := readManifest(root)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := filepath.ToSlash(strings.TrimPrefix(file, root+string(filepath.Separator)))
+		if d.Type()&fs.ModeSymlink != 0 || rel == manifestFile {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		perm := fi.Mode().Perm() &^ 0222
+		if err := os.Chmod(file, perm); err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			m.chmod(rel, perm)
+		}
+		return nil
+	})
+	if err != nil || m == nil {
+		return err
+	}
+	// The manifest may have been made read-only before.
+	file := filepath.Join(root, manifestFile)
+	if err := os.Chmod(file, 0644); err != nil {
+		return err
+	}
+	if err := m.write(root); err != nil {
+		return err
+	}
+	return os.Chmod(file, 0444)
+}
+
+// makeWritable restores the owner's write permission to the directories in
+// root, so that their contents can be removed or replaced. Files are left
+// read-only, as they may be hard links shared with other SDKs, except on
+// Windows, where read-only files can't be replaced.
+func makeWritable(root string) error {
+	return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && runtime.GOOS != "windows" || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if perm := fi.Mode().Perm(); perm&0200 == 0 {
+			return os.Chmod(file, perm|0200)
+		}
+		return nil
+	})
+}
+
+// removeAll is like os.RemoveAll, but first restores write permission to
+// the directories in dir if they are read-only, as "go clean -modcache"
+// does.
+func removeAll(dir string) error {
+	if err := os.RemoveAll(dir); err == nil {
+		return nil
+	}
+	makeWritable(dir)
+	return os.RemoveAll(dir)
+}
+
+// withWritableDir calls f with write permission restored to dir, if it
+// was read-only.
+func withWritableDir(dir string, f func() error) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if perm := fi.Mode().Perm(); perm&0200 == 0 {
+		if err := os.Chmod(dir, perm|0200); err != nil {
+			return err
+		}
+		defer os.Chmod(dir, perm)
+	}
+	return f()
+}
+
+// uninstallFlags parses the arguments of the uninstall command, args,
+// which takes no flags.
+func uninstallFlags(version string, args []string) {
+	fs := flag.NewFlagSet(version+" uninstall", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/zip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// checkWritable checks whether the directories and the files in root,
+// other than symbolic links, have write permission.
+func checkWritable(t *testing.T, root string, wantDirs, wantFiles bool) {
+	t.Helper()
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink != 0 {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		want := wantFiles
+		if d.IsDir() {
+			want = wantDirs
+		}
+		if got := fi.Mode().Perm()&0200 != 0; got != want {
+			t.Errorf("%s has mode %v; want writable %v", file, fi.Mode(), want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallReadOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directories can't be made read-only on Windows")
+	}
+	root := installTestGoroot(t)
+	checkWritable(t, root, false, false)
+	m, err := readManifest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := m.files["bin/go"]; e.mode.Perm()&0222 != 0 {
+		t.Errorf("manifest records mode %v for bin/go; want read-only", e.mode)
+	}
+	if r, err := verifyGoroot(root, m); err != nil || !r.ok() {
+		t.Errorf("verifying read-only install = %+v, %v", r, err)
+	}
+
+	// Repair keeps the GOROOT read-only.
+	file := filepath.Join(root, "src", "fmt", "print.go")
+	if err := os.Chmod(file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("package fmt // edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(root, "go1.99", true); err != nil {
+		t.Fatal(err)
+	}
+	checkWritable(t, root, false, false)
+
+	// A reinstall replaces the read-only GOROOT.
+	if err := os.Chmod(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, unpackedOkay)); err != nil {
+		t.Fatal(err)
+	}
+	if err := install(root, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	checkWritable(t, root, false, false)
+
+	if err := removeAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("removeAll left %v: %v", root, err)
+	}
+}
+
+func TestInstallWritable(t *testing.T) {
+	t.Setenv("GODL_READONLY", "0")
+	root := installTestGoroot(t)
+	checkWritable(t, root, true, true)
+}
+
+func TestMakeWritable(t *testing.T) {
+	root := makeTestSDK(t, t.TempDir(), "go1.99", map[string]string{"src/fmt/print.go": "package fmt\n"})
+	if err := os.Symlink("print.go", filepath.Join(root, "src", "fmt", "link.go")); err != nil {
+		t.Skip(err)
+	}
+	if err := makeReadOnly(root); err != nil {
+		t.Fatal(err)
+	}
+	checkWritable(t, root, false, false)
+	if err := makeWritable(root); err != nil {
+		t.Fatal(err)
+	}
+	// Files may be shared with other SDKs, so they are left read-only.
+	checkWritable(t, root, true, runtime.GOOS == "windows")
+}
+
+func TestUnpackZipModTime(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "go.zip")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2024, 5, 7, 16, 20, 0, 0, time.UTC)
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "go/VERSION", Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("go1.99"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "go")
+	if err := unpackZip(target, file, "go/", nil); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(target, "VERSION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("VERSION modified at %v; want %v", fi.ModTime(), modTime)
+	}
+}
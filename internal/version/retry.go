@@ -0,0 +1,216 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Defaults for the retry policy of network operations.
+const (
+	defaultRetryAttempts = 5
+	defaultRetryDeadline = 10 * time.Minute
+	maxRetryDelay        = 30 * time.Second
+)
+
+// retryBaseDelay is the delay before the first retry. It doubles with
+// every attempt.
+var retryBaseDelay = time.Second
+
+// A statusError is returned for an unexpected HTTP response status.
+type statusError struct {
+	err        error
+	statusCode int
+	retryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+// newStatusError returns a statusError for the response res, described by
+// err.
+func newStatusError(res *http.Response, err error) *statusError {
+	return &statusError{
+		err:        err,
+		statusCode: res.StatusCode,
+		retryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either
+// a number of seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// retryPolicy returns the maximum number of attempts of a network operation
+// and the time after which no new attempt is started, as set by
+// $GODL_RETRY_ATTEMPTS and $GODL_RETRY_DEADLINE.
+func retryPolicy() (attempts int, deadline time.Duration) {
+	attempts, deadline = defaultRetryAttempts, defaultRetryDeadline
+	if s := os.Getenv("GODL_RETRY_ATTEMPTS"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 1 {
+			attempts = n
+		} else {
+			log.Printf("ignoring invalid GODL_RETRY_ATTEMPTS=%q", s)
+		}
+	}
+	if s := os.Getenv("GODL_RETRY_DEADLINE"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			deadline = d
+		} else {
+			log.Printf("ignoring invalid GODL_RETRY_DEADLINE=%q", s)
+		}
+	}
+	return attempts, deadline
+}
+
+// retry calls f until it succeeds, fails with an error that isn't
+// transient, or the retry policy is exhausted, waiting with exponential
+// backoff and jitter between attempts. A delay requested by the server with
+// Retry-After is honored. The description what is used to log retries.
+func retry(what string, f func() error) error {
+	attempts, deadline := retryPolicy()
+	stop := time.Now().Add(deadline)
+	delay := retryBaseDelay
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || !isTransient(err) {
+			return err
+		}
+		if i == attempts {
+			return fmt.Errorf("%s: giving up after %d attempts: %w", what, i, err)
+		}
+		// Full jitter in [delay/2, delay).
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		var se *statusError
+		if errors.As(err, &se) && se.retryAfter > wait {
+			wait = se.retryAfter
+		}
+		if time.Now().Add(wait).After(stop) {
+			return fmt.Errorf("%s: giving up after %d attempts and %v: %w", what, i, deadline, err)
+		}
+		log.Printf("%s: %v; retrying in %v (attempt %d of %d)", what, err, wait.Round(time.Millisecond), i+1, attempts)
+		time.Sleep(wait)
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// isTransient reports whether err is likely to go away if the operation is
+// retried: a network error or a server error status.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		// The operation was abandoned, such as when another range of a
+		// parallel download failed.
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		switch se.statusCode {
+		case http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var (
+		netErr   net.Error
+		stallErr *stallError
+	)
+	return errors.As(err, &netErr) ||
+		errors.As(err, &stallErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// errReplayChanged is returned by a replayWriter when a retried download
+// differs from the bytes already passed on by an earlier attempt.
+var errReplayChanged = errors.New("download changed between attempts")
+
+// replayWriter passes the bytes of a download that is retried from the
+// beginning, or resumed from a partial file that is read back, to w,
+// skipping those that already were in an earlier attempt. Since w can't
+// take back the bytes it was passed, the skipped bytes must match them, or
+// what w got would not be what the SHA-256 of the download verifies.
+type replayWriter struct {
+	w       io.Writer
+	written int64     // bytes written to w
+	pos     int64     // position in the current attempt
+	sum     hash.Hash // SHA-256 of the bytes written to w
+	replay  hash.Hash // SHA-256 of the bytes skipped in the current attempt
+}
+
+// restart prepares the writer for a new attempt.
+func (r *replayWriter) restart() {
+	r.pos = 0
+	r.replay = sha256.New()
+}
+
+// check reports whether the current attempt, which completed, replayed all
+// the bytes written to w.
+func (r *replayWriter) check() error {
+	if r.pos < r.written {
+		return errReplayChanged
+	}
+	return nil
+}
+
+func (r *replayWriter) Write(buf []byte) (int, error) {
+	if r.sum == nil {
+		r.sum = sha256.New()
+	}
+	n := len(buf)
+	if skip := r.written - r.pos; skip > 0 {
+		if skip > int64(n) {
+			skip = int64(n)
+		}
+		r.replay.Write(buf[:skip])
+		buf = buf[skip:]
+		r.pos += skip
+		if r.pos < r.written {
+			return n, nil
+		}
+		if !bytes.Equal(r.replay.Sum(nil), r.sum.Sum(nil)) {
+			return n - len(buf), errReplayChanged
+		}
+	}
+	m, err := r.w.Write(buf)
+	r.sum.Write(buf[:m])
+	r.pos += int64(m)
+	r.written += int64(m)
+	if err == nil && m < len(buf) {
+		err = io.ErrShortWrite
+	}
+	return n - len(buf) + m, err
+}
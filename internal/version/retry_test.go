@@ -0,0 +1,101 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	var failures, requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	t.Setenv("GODL_RETRY_ATTEMPTS", "3")
+	failures, requests = 2, 0
+	if got, err := slurpURLToString(ts.URL); err != nil || got != "ok" {
+		t.Errorf("slurpURLToString after 2 failures = %q, %v; want %q", got, err, "ok")
+	}
+
+	failures, requests = 3, 0
+	_, err := slurpURLToString(ts.URL)
+	var se *statusError
+	if !errors.As(err, &se) || se.statusCode != http.StatusServiceUnavailable || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+		t.Errorf("slurpURLToString after 3 failures = %v; want error wrapping 503 status", err)
+	}
+
+	failures, requests = 0, 0
+	if _, err := slurpURLToString(ts.URL + "/missing"); err == nil || requests != 1 {
+		t.Errorf("slurpURLToString of missing file = %v after %d requests; want error after 1", err, requests)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v; want 3s", "3", got)
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %v; want about 1h", future, got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Errorf("parseRetryAfter(%q) = %v; want 0", "soon", got)
+	}
+}
+
+func TestReplayWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &replayWriter{w: &buf}
+	w.Write([]byte("hello, "))
+	w.Write([]byte("wor"))
+
+	// A retry that starts over.
+	w.restart()
+	w.Write([]byte("hel"))
+	w.Write([]byte("lo, world"))
+	w.Write([]byte("!"))
+	if got, want := buf.String(), "hello, world!"; got != want {
+		t.Errorf("replayed writes = %q; want %q", got, want)
+	}
+	if err := w.check(); err != nil {
+		t.Errorf("check after complete replay = %v", err)
+	}
+
+	// A retry that starts over with different bytes.
+	w.restart()
+	if _, err := w.Write([]byte("hello, there!")); err != errReplayChanged {
+		t.Errorf("replaying different bytes = %v; want %v", err, errReplayChanged)
+	}
+
+	// A retry that ends before replaying what was written.
+	w.restart()
+	w.Write([]byte("hello"))
+	if err := w.check(); err != errReplayChanged {
+		t.Errorf("check after short replay = %v; want %v", err, errReplayChanged)
+	}
+	if got, want := buf.String(), "hello, world!"; got != want {
+		t.Errorf("writes after failed replays = %q; want %q", got, want)
+	}
+}
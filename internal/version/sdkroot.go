@@ -0,0 +1,76 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// projectSDKDir is the name of the directory in which a repository keeps
+// its own SDKs.
+const projectSDKDir = ".sdk"
+
+// sdkRoot returns the directory in which SDKs are installed, by order of
+// precedence:
+//
+//   - $GOSDK, if set;
+//   - the .sdk directory of the current directory or of its nearest parent
+//     that has one, for repositories that keep their own SDKs, if
+//     GODL_PROJECT_SDK=1;
+//   - $XDG_DATA_HOME/golang-dl/sdk, defaulting to
+//     ~/.local/share/golang-dl/sdk, if GODL_XDG=1;
+//   - ~/sdk.
+//
+// The download command and the go command run by a wrapper resolve the same
+// directory, as long as they are run from the same directory.
+func sdkRoot() (string, error) {
+	if dir := os.Getenv("GOSDK"); dir != "" {
+		// The go command is run with GOROOT set to a directory in it.
+		return filepath.Abs(dir)
+	}
+	// A repository being worked on shouldn't choose the Go installed for
+	// the user unless asked to.
+	if os.Getenv("GODL_PROJECT_SDK") == "1" {
+		if dir, ok := findProjectSDK(); ok {
+			return dir, nil
+		}
+	}
+	home, err := homedir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %v", err)
+	}
+	if os.Getenv("GODL_XDG") == "1" {
+		data := os.Getenv("XDG_DATA_HOME")
+		if data == "" || !filepath.IsAbs(data) {
+			// The XDG Base Directory Specification says to ignore
+			// relative paths.
+			data = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(data, "golang-dl", "sdk"), nil
+	}
+	return filepath.Join(home, "sdk"), nil
+}
+
+// findProjectSDK returns the projectSDKDir directory in the current
+// directory or its nearest parent that has one.
+func findProjectSDK() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		sdk := filepath.Join(dir, projectSDKDir)
+		if fi, err := os.Stat(sdk); err == nil && fi.IsDir() {
+			return sdk, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
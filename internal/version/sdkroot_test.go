@@ -0,0 +1,79 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSDKRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("GOSDK", "")
+	t.Setenv("GODL_XDG", "")
+	t.Setenv("GODL_PROJECT_SDK", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "cmd", "tool")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want string) {
+		t.Helper()
+		got, err := sdkRoot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The temporary directories may be behind symbolic links, as on
+		// macOS, which os.Getwd resolves.
+		gotEval, _ := filepath.EvalSymlinks(got)
+		wantEval, _ := filepath.EvalSymlinks(want)
+		if got != want && (gotEval == "" || gotEval != wantEval) {
+			t.Errorf("sdkRoot() = %q; want %q", got, want)
+		}
+	}
+	check(filepath.Join(home, "sdk"))
+
+	t.Setenv("GODL_XDG", "1")
+	check(filepath.Join(home, ".local", "share", "golang-dl", "sdk"))
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	check(filepath.Join(data, "golang-dl", "sdk"))
+
+	// A project-local .sdk directory is only used if asked for, and then
+	// takes precedence.
+	project := filepath.Join(repo, projectSDKDir)
+	if err := os.Mkdir(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	check(filepath.Join(data, "golang-dl", "sdk"))
+	t.Setenv("GODL_PROJECT_SDK", "1")
+	check(project)
+
+	// $GOSDK takes precedence over everything, and is made absolute.
+	gosdk := t.TempDir()
+	t.Setenv("GOSDK", gosdk)
+	check(gosdk)
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, "sdks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOSDK", "sdks")
+	check(filepath.Join(repo, "sdks"))
+}
@@ -0,0 +1,165 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// buildSource reports whether to build Go from its source archive when
+// there is no binary release for the host, unless GODL_BUILD_SOURCE=0.
+func buildSource() bool {
+	return os.Getenv("GODL_BUILD_SOURCE") != "0"
+}
+
+// installFromSource installs a version of Go to targetDir by downloading
+// its source archive from the first of the mirrors that has it, and
+// building it with make.bash, for use once moved to finalDir. noRelease is
+// the error reporting that the mirrors have no binary release of the
+// version.
+func installFromSource(targetDir, finalDir, version string, mirrors []string, noRelease *noReleaseError) error {
+	var baseURLs []string
+	for _, baseURL := range mirrors {
+		if baseURL != proxyMirror {
+			baseURLs = append(baseURLs, baseURL)
+		}
+	}
+	if len(baseURLs) == 0 {
+		return noRelease
+	}
+	log.Printf("%s: %v; building from source", version, noRelease)
+	var err error
+	for i, baseURL := range baseURLs {
+		err = fetchArchive(targetDir, version, hostPlatform(), baseURL+version+".src.tar.gz")
+		if err == nil || i == len(baseURLs)-1 || !tryNextMirror(err) {
+			break
+		}
+		log.Printf("%s: %v; trying next mirror", version, err)
+	}
+	var srcErr *noReleaseError
+	if errors.As(err, &srcErr) {
+		return fmt.Errorf("%v, nor source release at %v", noRelease, srcErr.url)
+	}
+	if err != nil {
+		return err
+	}
+	if err := makeGo(targetDir, finalDir); err != nil {
+		return err
+	}
+	// The manifest of the source archive doesn't list the built files.
+	m, err := treeManifest(targetDir)
+	if err != nil {
+		return err
+	}
+	if err := m.write(targetDir); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(targetDir, unpackedOkay), nil, 0644)
+}
+
+// makeGo builds the Go source tree in root with its make script, for use
+// once moved to finalRoot, using $GOROOT_BOOTSTRAP, or else the newest Go
+// release installed next to root, as the bootstrap toolchain.
+func makeGo(root, finalRoot string) error {
+	bootstrap := os.Getenv("GOROOT_BOOTSTRAP")
+	if bootstrap == "" {
+		bootstrap = findBootstrap(filepath.Dir(root))
+	}
+	env := append(os.Environ(), "GOROOT_FINAL="+finalRoot)
+	if bootstrap != "" {
+		log.Printf("Building Go in %v with bootstrap toolchain %v ...", root, bootstrap)
+		env = append(env, "GOROOT_BOOTSTRAP="+bootstrap)
+	} else {
+		// make.bash looks for one itself, such as the go command in $PATH.
+		log.Printf("Building Go in %v ...", root)
+	}
+	return runMake(root, env)
+}
+
+// findBootstrap returns the directory of the newest Go release installed
+// for the host in sdkDir, or the empty string if there is none.
+func findBootstrap(sdkDir string) string {
+	entries, err := os.ReadDir(sdkDir)
+	if err != nil {
+		return ""
+	}
+	var best string
+	var bestVersion goVersion
+	for _, e := range entries {
+		v, ok := parseGoVersion(e.Name())
+		if !ok || (best != "" && !bestVersion.less(v)) {
+			continue
+		}
+		dir := filepath.Join(sdkDir, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, unpackedOkay)); err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "bin", "go"+exe())); err != nil {
+			continue
+		}
+		best, bestVersion = dir, v
+	}
+	return best
+}
+
+// A goVersion is a parsed Go release version: its major, minor and patch
+// numbers, followed by the kind of release (beta, rc or final) and the
+// number of the beta or release candidate.
+type goVersion [5]int
+
+// Kinds of releases, in order.
+const (
+	betaRelease = iota
+	rcRelease
+	finalRelease
+)
+
+// parseGoVersion parses a Go release version such as "go1.22.3",
+// "go1.21rc2" or "go1.9beta1".
+func parseGoVersion(s string) (v goVersion, ok bool) {
+	if !strings.HasPrefix(s, "go") {
+		return v, false
+	}
+	s = strings.TrimPrefix(s, "go")
+	v[3] = finalRelease
+	for kind, pre := range []string{betaRelease: "beta", rcRelease: "rc"} {
+		if i := strings.Index(s, pre); i >= 0 {
+			n, err := strconv.Atoi(s[i+len(pre):])
+			if err != nil || n < 0 {
+				return v, false
+			}
+			s, v[3], v[4] = s[:i], kind, n
+			break
+		}
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || strconv.Itoa(n) != p {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+// less reports whether v is an earlier release than w.
+func (v goVersion) less(w goVersion) bool {
+	for i := range v {
+		if v[i] != w[i] {
+			return v[i] < w[i]
+		}
+	}
+	return false
+}
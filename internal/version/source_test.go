@@ -0,0 +1,144 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseGoVersion(t *testing.T) {
+	versions := []string{"go1", "go1.9beta1", "go1.9rc2", "go1.9", "go1.9.1", "go1.10", "go1.21rc1", "go1.21.0", "go1.22.3"}
+	var prev goVersion
+	for i, s := range versions {
+		v, ok := parseGoVersion(s)
+		if !ok {
+			t.Fatalf("parseGoVersion(%q) failed", s)
+		}
+		if i > 0 && !prev.less(v) {
+			t.Errorf("%s is not earlier than %s", versions[i-1], s)
+		}
+		prev = v
+	}
+	for _, s := range []string{"gotip", "go1.22.3.linux-arm64", "go1.22rc", "go1..2", "1.22"} {
+		if _, ok := parseGoVersion(s); ok {
+			t.Errorf("parseGoVersion(%q) succeeded", s)
+		}
+	}
+}
+
+// testSourceArchive returns a source archive whose make.bash records the
+// bootstrap toolchain in bin/bootstrap.
+func testSourceArchive() []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	script := "#!/bin/sh\nmkdir -p ../bin && echo \"$GOROOT_BOOTSTRAP\" > ../bin/bootstrap\n"
+	tw.WriteHeader(&tar.Header{Name: "go/src/make.bash", Mode: 0755, Size: int64(len(script))})
+	tw.Write([]byte(script))
+	tw.Close()
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestInstallFromSource(t *testing.T) {
+	if makeScript() != "make.bash" {
+		t.Skipf("no make.bash on %s", runtime.GOOS)
+	}
+	t.Setenv("GOROOT_BOOTSTRAP", "")
+	sdk := tempSDKDir(t)
+	for _, v := range []string{"go1.50", "go1.60", "go1.70", "gotip"} {
+		dir := filepath.Join(sdk, v)
+		if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "bin", "go"+exe()), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if v != "go1.70" { // not completely installed
+			if err := os.WriteFile(filepath.Join(dir, unpackedOkay), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	archive := testSourceArchive()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/go1.99.src.tar.gz":
+			w.Write(archive)
+		case "/go1.99.src.tar.gz.sha256":
+			fmt.Fprintf(w, "%x\n", sha256.Sum256(archive))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	dir := filepath.Join(sdk, "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "bin", "bootstrap"))
+	if want := filepath.Join(sdk, "go1.60"); err != nil || strings.TrimSpace(string(got)) != want {
+		t.Errorf("bootstrap = %q, %v; want %q", got, err, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, unpackedOkay)); err != nil {
+		t.Error(err)
+	}
+
+	err = install(filepath.Join(sdk, "go1.100"), "go1.100", hostPlatform())
+	if err == nil || !strings.Contains(err.Error(), "nor source release") {
+		t.Errorf("install = %v; want no binary or source release error", err)
+	}
+}
+
+func TestInstallFromSourceReleaseIndex(t *testing.T) {
+	if makeScript() != "make.bash" {
+		t.Skipf("no make.bash on %s", runtime.GOOS)
+	}
+	bootstrap := t.TempDir()
+	t.Setenv("GOROOT_BOOTSTRAP", bootstrap)
+	t.Setenv("GODL_VERIFY", "index")
+	t.Setenv("GODL_CACHE", t.TempDir())
+
+	// The release index only lists the source archive, as for the old
+	// releases of some platforms.
+	archive := testSourceArchive()
+	serveReleaseIndex(t, releaseFile{
+		Filename: "go1.99.src.tar.gz",
+		SHA256:   fmt.Sprintf("%x", sha256.Sum256(archive)),
+		Size:     int64(len(archive)),
+	})
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/go1.99.src.tar.gz" {
+			w.Write(archive)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "bin", "bootstrap"))
+	if err != nil || strings.TrimSpace(string(got)) != bootstrap {
+		t.Errorf("bootstrap = %q, %v; want %q", got, err, bootstrap)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/mod/sumdb"
+)
+
+// sumdbOps implements sumdb.ClientOps for the checksum database at url,
+// with the verifier key vkey. Like the go command, it keeps the latest
+// tree signed by the database in dir, so that a later lookup is refused if
+// it is served an older or forked tree, and caches the verified records
+// and tiles there.
+type sumdbOps struct {
+	url  string
+	vkey string
+	dir  string
+
+	mu sync.Mutex // serializes WriteConfig
+}
+
+func (ops *sumdbOps) ReadRemote(path string) ([]byte, error) {
+	data, err := slurpURLToString(ops.url + path)
+	return []byte(data), err
+}
+
+func (ops *sumdbOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(ops.vkey), nil
+	}
+	data, err := os.ReadFile(ops.file(file))
+	if os.IsNotExist(err) {
+		// No tree has been seen yet.
+		return nil, nil
+	}
+	return data, err
+}
+
+func (ops *sumdbOps) WriteConfig(file string, old, new []byte) error {
+	ops.mu.Lock()
+	defer ops.mu.Unlock()
+	data, err := ops.ReadConfig(file)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, old) {
+		return sumdb.ErrWriteConflict
+	}
+	return writeFileAtomic(ops.file(file), new)
+}
+
+func (ops *sumdbOps) ReadCache(file string) ([]byte, error) {
+	return os.ReadFile(ops.file(file))
+}
+
+func (ops *sumdbOps) WriteCache(file string, data []byte) {
+	// The cache is only an optimization.
+	writeFileAtomic(ops.file(file), data)
+}
+
+func (ops *sumdbOps) Log(msg string) {
+	log.Print(msg)
+}
+
+func (ops *sumdbOps) SecurityError(msg string) {
+	// The lookup then fails with sumdb.ErrSecurity.
+	log.Print(msg)
+}
+
+// file returns the name of the configuration or cache file in ops.dir.
+func (ops *sumdbOps) file(name string) string {
+	return filepath.Join(ops.dir, filepath.FromSlash(name))
+}
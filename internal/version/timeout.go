@@ -0,0 +1,116 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Default timeouts for downloads.
+const (
+	defaultConnectTimeout  = 30 * time.Second
+	defaultResponseTimeout = time.Minute
+	defaultIdleTimeout     = time.Minute
+)
+
+// downloadTimeouts returns the time allowed to establish a connection
+// (including the TLS handshake), to receive response headers once the
+// request is sent, and between two reads of the response body, as set by
+// $GODL_CONNECT_TIMEOUT, $GODL_RESPONSE_TIMEOUT and $GODL_IDLE_TIMEOUT.
+// A zero duration disables the timeout.
+func downloadTimeouts() (connect, response, idle time.Duration) {
+	return envDuration("GODL_CONNECT_TIMEOUT", defaultConnectTimeout),
+		envDuration("GODL_RESPONSE_TIMEOUT", defaultResponseTimeout),
+		envDuration("GODL_IDLE_TIMEOUT", defaultIdleTimeout)
+}
+
+// envDuration returns the duration in the environment variable key, or def
+// if it is unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		log.Printf("ignoring invalid %s=%q", key, s)
+		return def
+	}
+	return d
+}
+
+// A stallError is returned when a download receives no data for longer
+// than the idle timeout.
+type stallError struct {
+	idle     time.Duration
+	received int64 // bytes of the file received so far
+	total    int64 // size of the file, or -1 if unknown
+}
+
+func (e *stallError) Error() string {
+	if e.total < 0 {
+		return fmt.Sprintf("download stalled: no data for %v after receiving %d bytes", e.idle, e.received)
+	}
+	return fmt.Sprintf("download stalled: no data for %v after receiving %d of %d bytes", e.idle, e.received, e.total)
+}
+
+// A stallWatcher cancels a download when no bytes are written to it for
+// longer than the idle timeout. It is meant to be part of the writer chain
+// that the response body is copied to.
+type stallWatcher struct {
+	idle    time.Duration
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	stalled int32 // accessed atomically
+}
+
+// watchStalls returns a context derived from ctx, to make the request
+// with, and a stallWatcher that cancels it once started. If idle is zero,
+// the watcher never cancels the request.
+func watchStalls(ctx context.Context, idle time.Duration) (context.Context, *stallWatcher) {
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, &stallWatcher{idle: idle, cancel: cancel}
+}
+
+// start starts watching for stalls. It is called once the response headers
+// are received.
+func (w *stallWatcher) start() {
+	if w.idle == 0 {
+		return
+	}
+	w.timer = time.AfterFunc(w.idle, func() {
+		atomic.StoreInt32(&w.stalled, 1)
+		w.cancel()
+	})
+}
+
+// stop stops watching for stalls and releases the request context.
+func (w *stallWatcher) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.cancel()
+}
+
+func (w *stallWatcher) Write(buf []byte) (int, error) {
+	if w.timer != nil {
+		w.timer.Reset(w.idle)
+	}
+	return len(buf), nil
+}
+
+// check returns a stallError in place of err if the download was canceled
+// because it stalled.
+func (w *stallWatcher) check(err error, received, total int64) error {
+	if err != nil && atomic.LoadInt32(&w.stalled) == 1 {
+		return &stallError{idle: w.idle, received: received, total: total}
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCopyFromURLStall(t *testing.T) {
+	t.Setenv("GODL_IDLE_TIMEOUT", "100ms")
+	done := make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(2000))
+		w.Write(make([]byte, 1000))
+		w.(http.Flusher).Flush()
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	start := time.Now()
+	_, err := copyFromURL(filepath.Join(t.TempDir(), "go.tar.gz"), ts.URL, nil)
+	var se *stallError
+	if !errors.As(err, &se) {
+		t.Fatalf("copyFromURL = %v; want stall error", err)
+	}
+	if se.received != 1000 || se.total != 2000 {
+		t.Errorf("stall after %d of %d bytes; want 1000 of 2000", se.received, se.total)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("stall detected after %v", d)
+	}
+	if !isTransient(err) {
+		t.Errorf("stall error is not transient")
+	}
+}
+
+func TestResponseTimeout(t *testing.T) {
+	t.Setenv("GODL_RESPONSE_TIMEOUT", "100ms")
+	done := make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	_, err := copyFromURL(filepath.Join(t.TempDir(), "go.tar.gz"), ts.URL, nil)
+	if err == nil || !isTransient(err) {
+		t.Errorf("copyFromURL = %v; want transient timeout error", err)
+	}
+}
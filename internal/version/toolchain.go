@@ -0,0 +1,300 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// proxyMirror is the entry of $GODL_MIRRORS that selects downloading the
+// golang.org/toolchain module from the module proxies in $GOPROXY instead
+// of a release archive.
+const proxyMirror = "goproxy"
+
+// toolchainModule is the path of the module that holds a copy of every
+// release since Go 1.21.
+const toolchainModule = "golang.org/toolchain"
+
+// sumGolangOrgKey is the verifier key of the sum.golang.org checksum
+// database.
+const sumGolangOrgKey = "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8"
+
+// toolchainModuleVersion returns the version of the golang.org/toolchain
+// module holding the given Go version for platform p.
+func toolchainModuleVersion(version string, p platform) string {
+	return "v0.0.1-" + version + "." + p.goos + "-" + p.goarch
+}
+
+// installFromProxy installs a version of Go to targetDir by downloading the
+// golang.org/toolchain module from the module proxies in $GOPROXY, like the
+// go command does when switching toolchains. The module zip is verified
+// against its hash in the checksum database named by $GOSUMDB.
+func installFromProxy(targetDir, version string, p platform) error {
+	modVer := toolchainModuleVersion(version, p)
+	if compiledSHA256 != nil {
+		// The checksums compiled into the wrapper are those of the release
+		// archives, which the module zip can't be verified against.
+		return fmt.Errorf("%w for %s@%s compiled into wrapper", errNoChecksum, toolchainModule, modVer)
+	}
+	proxyURL, err := findToolchainProxy(version, p, modVer)
+	if err != nil {
+		return err
+	}
+	wantSum, err := lookupSum(proxyURL, toolchainModule, modVer)
+	if err != nil {
+		return fmt.Errorf("looking up checksum of %s@%s: %v", toolchainModule, modVer, err)
+	}
+	zipURL := proxyURL + toolchainModule + "/@v/" + modVer + ".zip"
+	zipFile := filepath.Join(targetDir, modVer+".zip")
+	err = retry("downloading "+zipURL, func() error {
+		_, err := copyFromURL(zipFile, zipURL, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error downloading %v: %w", zipURL, err)
+	}
+	// The module zip can't be used to repair the GOROOT, as it holds no
+	// file modes, so it isn't kept like a release archive.
+	defer os.Remove(zipFile)
+	gotSum, err := hashZip(zipFile)
+	if err != nil {
+		return err
+	}
+	if gotSum != wantSum {
+		return fmt.Errorf("%s corrupt? has hash %s; checksum database has %s", zipFile, gotSum, wantSum)
+	}
+	log.Printf("Unpacking %v ...", zipFile)
+	// The manifest names no archive, as the module zip isn't kept.
+	m := newManifest("", "")
+	if err := unpackZip(targetDir, zipFile, toolchainModule+"@"+modVer+"/", m); err != nil {
+		return fmt.Errorf("extracting archive %v: %v", zipFile, err)
+	}
+	if err := setToolchainExecBits(targetDir, m); err != nil {
+		return err
+	}
+	if err := m.write(targetDir); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(targetDir, unpackedOkay), nil, 0644)
+}
+
+// findToolchainProxy returns the URL of the first module proxy in $GOPROXY
+// that has version modVer of the golang.org/toolchain module.
+func findToolchainProxy(version string, plat platform, modVer string) (string, error) {
+	proxies, err := goproxyURLs()
+	if err != nil {
+		return "", err
+	}
+	c, err := httpClient()
+	if err != nil {
+		return "", err
+	}
+	infoPath := toolchainModule + "/@v/" + modVer + ".info"
+	var lastErr error
+	for _, proxy := range proxies {
+		var res *http.Response
+		err := retry("fetching "+proxy.url+infoPath, func() (err error) {
+			res, err = c.Get(proxy.url + infoPath)
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			switch res.StatusCode {
+			case http.StatusOK, http.StatusNotFound, http.StatusGone:
+				return nil
+			}
+			return newStatusError(res, fmt.Errorf("%s: %v", proxy.url+infoPath, res.Status))
+		})
+		if err == nil {
+			if res.StatusCode == http.StatusOK {
+				return proxy.url, nil
+			}
+			lastErr = &noReleaseError{version: version, platform: plat, url: proxy.url + infoPath}
+			continue
+		}
+		if !proxy.fallback {
+			return "", err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return "", errors.New("no module proxy in GOPROXY")
+	}
+	return "", lastErr
+}
+
+// A goproxy is an entry of $GOPROXY.
+type goproxy struct {
+	url string // base URL, ending in a slash
+
+	// fallback reports whether to try the next proxy on any error, rather
+	// than only if this one doesn't have the module.
+	fallback bool
+}
+
+// goproxyURLs returns the module proxies listed in $GOPROXY. The "direct"
+// entry is skipped, as the toolchain module can only be served by a proxy.
+func goproxyURLs() ([]goproxy, error) {
+	list := os.Getenv("GOPROXY")
+	if list == "" {
+		list = "https://proxy.golang.org,direct"
+	}
+	var proxies []goproxy
+	for list != "" {
+		var entry string
+		fallback := false
+		if i := strings.IndexAny(list, ",|"); i >= 0 {
+			entry, fallback, list = list[:i], list[i] == '|', list[i+1:]
+		} else {
+			entry, list = list, ""
+		}
+		entry = strings.TrimSpace(entry)
+		switch entry {
+		case "", "direct":
+			continue
+		case "off":
+			return nil, errors.New("module lookups disabled by GOPROXY=off")
+		}
+		if !strings.HasSuffix(entry, "/") {
+			entry += "/"
+		}
+		proxies = append(proxies, goproxy{url: entry, fallback: fallback})
+	}
+	return proxies, nil
+}
+
+// lookupSum returns the hash of the zip of the module path@version from
+// the checksum database named by $GOSUMDB. The database is accessed
+// through the module proxy at proxyURL if it supports it, and directly
+// otherwise. Neither is trusted: as in the go command, the record holding
+// the hash is only accepted once it is proven to be in the tree signed by
+// the database, and that tree to extend the latest one seen before.
+func lookupSum(proxyURL, path, version string) (string, error) {
+	name, vkey, dbURL, err := sumdbConfig()
+	if err != nil {
+		return "", err
+	}
+	c, err := httpClient()
+	if err != nil {
+		return "", err
+	}
+	if res, err := c.Get(proxyURL + "sumdb/" + name + "/supported"); err == nil {
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			dbURL = proxyURL + "sumdb/" + name
+		}
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	client := sumdb.NewClient(&sumdbOps{url: dbURL, vkey: vkey, dir: filepath.Join(dir, "sumdb")})
+	lines, err := client.Lookup(path, version)
+	if err != nil {
+		return "", err
+	}
+	prefix := path + " " + version + " "
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s@%s", path, version)
+}
+
+// sumdbConfig returns the name, verifier key and URL of the checksum
+// database named by $GOSUMDB.
+func sumdbConfig() (name, vkey, dbURL string, err error) {
+	db := strings.TrimSpace(os.Getenv("GOSUMDB"))
+	switch db {
+	case "off":
+		return "", "", "", errors.New("checksum database disabled by GOSUMDB=off; refusing to install an unverified toolchain")
+	case "", "sum.golang.org":
+		db = sumGolangOrgKey
+	case "sum.golang.google.cn":
+		db = sumGolangOrgKey + " https://sum.golang.google.cn"
+	}
+	vkey, dbURL, _ = strings.Cut(db, " ")
+	name, _, _ = strings.Cut(vkey, "+")
+	if dbURL = strings.TrimSpace(dbURL); dbURL == "" {
+		dbURL = "https://" + name
+	}
+	return name, vkey, strings.TrimSuffix(dbURL, "/"), nil
+}
+
+// hashZip returns the go.sum hash of the files in a module zip, like
+// dirhash.HashZip, reporting the progress of reading them.
+func hashZip(zipFile string) (string, error) {
+	zr, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	var names []string
+	files := make(map[string]*zip.File)
+	var size int64
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		files[f.Name] = f
+		size += int64(f.UncompressedSize64)
+	}
+	pw := newProgressWriter(io.Discard, phaseVerifying, 0, size)
+	sum, err := dirhash.Hash1(names, func(name string) (io.ReadCloser, error) {
+		r, err := files[name].Open()
+		if err != nil {
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(r, pw), r}, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	pw.done()
+	return sum, nil
+}
+
+// setToolchainExecBits makes the commands of a toolchain unpacked from a
+// module zip executable, as module zips don't record file modes, and
+// records their modes in m.
+func setToolchainExecBits(root string, m *manifest) error {
+	dirs := []string{filepath.Join(root, "bin")}
+	tools, _ := filepath.Glob(filepath.Join(root, "pkg", "tool", "*"))
+	dirs = append(dirs, tools...)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			file := filepath.Join(dir, e.Name())
+			if err := os.Chmod(file, 0755); err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			m.chmod(filepath.ToSlash(rel), 0755)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func writeTestZip(t *testing.T, file string, names ...string) {
+	t.Helper()
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(w, "contents of %s\n", name)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHashZip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "m.zip")
+	writeTestZip(t, file, "m@v1/go.mod", "m@v1/bin/go", "m@v1/README")
+	got, err := hashZip(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Computed with golang.org/x/mod/sumdb/dirhash.HashZip.
+	const want = "h1:X3r99tmGEb5C4H5+qJlRHCxyn+CTDlxDNc5nL7VdazY="
+	if got != want {
+		t.Errorf("hashZip = %s; want %s", got, want)
+	}
+}
+
+// fakeSumDB is a checksum database signing its tree heads with a freshly
+// generated key. Its tree starts with filler records, so that proofs need
+// several tiles.
+type fakeSumDB struct {
+	skey, vkey string
+	sums       map[string]string // go.sum hash by "path version"
+	server     *sumdb.TestServer
+
+	// replace, if not nil, rewrites the responses to lookups.
+	replace *strings.Replacer
+}
+
+func newFakeSumDB(t *testing.T, name string) *fakeSumDB {
+	skey, vkey, err := note.GenerateKey(nil, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &fakeSumDB{skey: skey, vkey: vkey}
+	db.init()
+	return db
+}
+
+// fork returns a database with the same key as db, but another tree.
+func (db *fakeSumDB) fork() *fakeSumDB {
+	f := &fakeSumDB{skey: db.skey, vkey: db.vkey}
+	f.init()
+	return f
+}
+
+func (db *fakeSumDB) init() {
+	db.sums = make(map[string]string)
+	db.server = sumdb.NewTestServer(db.skey, func(path, vers string) ([]byte, error) {
+		sum, ok := db.sums[path+" "+vers]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(fmt.Sprintf("%s %s %s\n", path, vers, sum)), nil
+	})
+	for i := 0; i < 300; i++ {
+		db.record(fmt.Sprintf("example.com/m%d", i), "v1.0.0", "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	}
+}
+
+// add adds a record of the hash of path@vers to the tree, followed by
+// filler records.
+func (db *fakeSumDB) add(path, vers, sum string) {
+	db.record(path, vers, sum)
+	for i := 0; i < 50; i++ {
+		db.record(fmt.Sprintf("example.com/n%d", len(db.sums)), "v1.0.0", "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	}
+}
+
+func (db *fakeSumDB) record(path, vers, sum string) {
+	db.sums[path+" "+vers] = sum
+	db.server.Lookup(context.Background(), module.Version{Path: path, Version: vers})
+}
+
+func (db *fakeSumDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv := sumdb.NewServer(db.server)
+	switch {
+	case r.URL.Path == "/supported":
+	case db.replace != nil && strings.HasPrefix(r.URL.Path, "/lookup/"):
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, r)
+		w.WriteHeader(rec.Code)
+		db.replace.WriteString(w, rec.Body.String())
+	default:
+		srv.ServeHTTP(w, r)
+	}
+}
+
+func TestInstallFromProxy(t *testing.T) {
+	t.Setenv("GODL_CACHE", t.TempDir())
+	modVer := toolchainModuleVersion("go1.99", hostPlatform())
+	prefix := toolchainModule + "@" + modVer + "/"
+	zipFile := filepath.Join(t.TempDir(), "toolchain.zip")
+	writeTestZip(t, zipFile, prefix+"bin/go", prefix+"pkg/tool/fake/compile", prefix+"VERSION")
+	zipData, err := os.ReadFile(zipFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := hashZip(zipFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := newFakeSumDB(t, "sum.example.com")
+	db.add(toolchainModule, modVer, sum)
+	empty := httptest.NewServer(http.NotFoundHandler())
+	defer empty.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/proxy/sumdb/sum.example.com/") {
+			http.StripPrefix("/proxy/sumdb/sum.example.com", db).ServeHTTP(w, r)
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/proxy/") {
+		case toolchainModule + "/@v/" + modVer + ".info":
+			fmt.Fprintf(w, `{"Version": %q}`, modVer)
+		case toolchainModule + "/@v/" + modVer + ".zip":
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(zipData))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer proxy.Close()
+	t.Setenv("GOPROXY", empty.URL+","+proxy.URL+"/proxy,direct")
+	t.Setenv("GOSUMDB", db.vkey+" https://sum.example.com.invalid")
+	t.Setenv("GODL_MIRRORS", proxyMirror)
+
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "VERSION")); err != nil || string(got) != "contents of "+prefix+"VERSION\n" {
+		t.Errorf("VERSION = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, modVer+".zip")); !os.IsNotExist(err) {
+		t.Errorf("module zip kept in GOROOT: %v", err)
+	}
+	for _, f := range []string{"bin/go", "pkg/tool/fake/compile"} {
+		fi, err := os.Stat(filepath.Join(dir, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm()&0100 == 0 {
+			t.Errorf("%s has mode %v; want executable", f, fi.Mode())
+		}
+	}
+
+	// Once a tree has been seen, a fork of it must be rejected.
+	fork := db.fork()
+	fork.add("example.com/forked", "v1.0.0", "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	db = fork
+	if _, err := lookupSum(proxy.URL+"/proxy/", "example.com/forked", "v1.0.0"); err == nil || !strings.Contains(err.Error(), sumdb.ErrSecurity.Error()) {
+		t.Errorf("lookupSum in forked tree = %v; want %v", err, sumdb.ErrSecurity)
+	}
+
+	// A record forged by the proxy must be rejected.
+	t.Setenv("GODL_CACHE", t.TempDir())
+	db = newFakeSumDB(t, "sum.example.com")
+	db.add(toolchainModule, modVer, sum)
+	t.Setenv("GOSUMDB", db.vkey+" https://sum.example.com.invalid")
+	bad := "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	db.replace = strings.NewReplacer(sum, bad)
+	dir = filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err == nil || !strings.Contains(err.Error(), "cannot authenticate record") {
+		t.Errorf("install with forged record = %v; want error", err)
+	}
+
+	// A different hash in the checksum database must be rejected.
+	t.Setenv("GODL_CACHE", t.TempDir())
+	db = newFakeSumDB(t, "sum.example.com")
+	db.add(toolchainModule, modVer, bad)
+	t.Setenv("GOSUMDB", db.vkey+" https://sum.example.com.invalid")
+	dir = filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err == nil || !strings.Contains(err.Error(), "checksum database has") {
+		t.Errorf("install = %v; want checksum mismatch", err)
+	}
+}
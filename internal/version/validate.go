@@ -0,0 +1,209 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// An entryValidator checks the entries of an archive as it is unpacked,
+// so that a malicious archive can't write outside the directory it is
+// unpacked to, or create anything but files, directories and links there.
+//
+// Symbolic links are only created by makeSymlinks, once all other entries
+// are unpacked, so that nothing is ever unpacked through one, and once
+// their targets are known to stay inside the directory.
+type entryValidator struct {
+	prefix string                 // directory holding all entries, such as "go/"
+	seen   map[string]fs.FileMode // modes of the entries so far, by relative path
+	dirs   map[string]bool        // parent directories of the entries so far
+	links  map[string]string      // targets of the symbolic links so far
+}
+
+func newEntryValidator(prefix string) *entryValidator {
+	return &entryValidator{
+		prefix: prefix,
+		seen:   make(map[string]fs.FileMode),
+		dirs:   make(map[string]bool),
+		links:  make(map[string]string),
+	}
+}
+
+// unsafeModes are the file mode bits that no unpacked entry may have.
+const unsafeModes = fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky |
+	fs.ModeDevice | fs.ModeCharDevice | fs.ModeNamedPipe | fs.ModeSocket
+
+// maxSymlinks is the most symbolic links followed to resolve a path, as by
+// Linux.
+const maxSymlinks = 40
+
+// check checks the entry of the archive with the given name and mode, and
+// returns its path relative to the directory the archive is unpacked to,
+// with forward slashes. The path is empty for the entry of the prefix
+// directory itself.
+//
+// The name must be a relative path, with forward slashes, of a file in the
+// prefix directory, without "." or ".." elements or symbolic links, and it
+// must not repeat that of an earlier entry.
+func (v *entryValidator) check(name string, mode fs.FileMode) (string, error) {
+	if mode&unsafeModes != 0 {
+		return "", fmt.Errorf("archive entry %q has unsupported mode %v", name, mode)
+	}
+	rel, err := v.relPath(name, mode.IsDir())
+	if err != nil {
+		return "", err
+	}
+	if rel == "" && !mode.IsDir() {
+		return "", fmt.Errorf("archive entry %q is not a directory", name)
+	}
+	if _, ok := v.seen[rel]; ok {
+		return "", fmt.Errorf("archive has duplicate entry %q", name)
+	}
+	if mode&fs.ModeSymlink != 0 && v.dirs[rel] {
+		return "", fmt.Errorf("archive entry %q is a symbolic link to replace a directory", name)
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if _, ok := v.links[dir]; ok {
+			return "", fmt.Errorf("archive entry %q is inside symbolic link %s%s", name, v.prefix, dir)
+		}
+		v.dirs[dir] = true
+	}
+	v.seen[rel] = mode
+	return rel, nil
+}
+
+// relPath returns the path relative to the prefix directory of the entry
+// named name, which must be a clean relative path inside it.
+func (v *entryValidator) relPath(name string, isDir bool) (string, error) {
+	if strings.Contains(name, `\`) {
+		return "", fmt.Errorf("archive entry %q contains a backslash", name)
+	}
+	// The manifest lists one file per line.
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("archive entry %q contains a control character", name)
+	}
+	if strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("archive entry %q is an absolute path", name)
+	}
+	var rel string
+	switch {
+	case strings.HasPrefix(name, v.prefix):
+		rel = name[len(v.prefix):]
+	case name+"/" == v.prefix:
+		rel = ""
+	default:
+		return "", fmt.Errorf("archive entry %q is outside %s", name, v.prefix)
+	}
+	if isDir {
+		rel = strings.TrimSuffix(rel, "/")
+	}
+	if rel != "" {
+		for _, elem := range strings.Split(rel, "/") {
+			switch elem {
+			case "", ".", "..":
+				return "", fmt.Errorf("archive entry %q is not a clean path", name)
+			}
+		}
+	}
+	return rel, nil
+}
+
+// checkSymlink checks the symbolic link entry at rel, as returned by
+// check, to target, and records it to be made by makeSymlinks. The target
+// must be a relative path with forward slashes.
+func (v *entryValidator) checkSymlink(rel, target string) error {
+	name := v.prefix + rel
+	switch {
+	case target == "":
+		return fmt.Errorf("archive entry %q is a symbolic link to nothing", name)
+	case strings.Contains(target, `\`):
+		return fmt.Errorf("archive entry %q is a symbolic link to %q, which contains a backslash", name, target)
+	case strings.HasPrefix(target, "/"):
+		return fmt.Errorf("archive entry %q is a symbolic link to absolute path %q", name, target)
+	}
+	v.links[rel] = target
+	return nil
+}
+
+// checkHardlink checks the hard link entry at rel, as returned by check, to
+// the entry named linkname, which must be an earlier regular file, and
+// returns the path of linkname relative to the prefix directory.
+func (v *entryValidator) checkHardlink(rel, linkname string) (string, error) {
+	target, err := v.relPath(linkname, false)
+	if err != nil {
+		return "", fmt.Errorf("archive entry %q is a hard link: %v", v.prefix+rel, err)
+	}
+	if mode, ok := v.seen[target]; !ok || !mode.IsRegular() || target == rel {
+		return "", fmt.Errorf("archive entry %q is a hard link to %q, which is not an earlier file", v.prefix+rel, linkname)
+	}
+	return target, nil
+}
+
+// makeSymlinks makes the symbolic links recorded by checkSymlink in
+// targetDir, after checking that their targets stay inside it, even when
+// following the other links.
+func (v *entryValidator) makeSymlinks(targetDir string) error {
+	rels := make([]string, 0, len(v.links))
+	for rel := range v.links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		target := v.links[rel]
+		// Don't clean the path: a/.. is not a if a is a symbolic link.
+		if !v.inside(path.Dir(rel) + "/" + target) {
+			return fmt.Errorf("archive entry %q is a symbolic link to %q, which doesn't resolve inside %s", v.prefix+rel, target, v.prefix)
+		}
+	}
+	for _, rel := range rels {
+		abs := filepath.Join(targetDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(filepath.FromSlash(v.links[rel]), abs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inside reports whether the path p, relative to the prefix directory but
+// not necessarily clean, stays inside it when resolving the symbolic links
+// recorded by checkSymlink, which may be in any order.
+func (v *entryValidator) inside(p string) bool {
+	var dir []string // resolved path so far
+	elems := strings.Split(p, "/")
+	followed := 0
+	for len(elems) > 0 {
+		elem := elems[0]
+		elems = elems[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(dir) == 0 {
+				return false
+			}
+			dir = dir[:len(dir)-1]
+			continue
+		}
+		dir = append(dir, elem)
+		if target, ok := v.links[strings.Join(dir, "/")]; ok {
+			if followed++; followed > maxSymlinks {
+				return false
+			}
+			dir = dir[:len(dir)-1]
+			elems = append(strings.Split(target, "/"), elems...)
+		}
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A testEntry is an entry of a crafted archive. A symbolic link has the
+// ModeSymlink mode and its target as link; a hard link has a regular mode
+// and the name of the entry it links to as link.
+type testEntry struct {
+	name string
+	mode fs.FileMode
+	body string
+	link string
+}
+
+// craftedArchives is a corpus of archives, each unpacked both as a tar.gz
+// and a zip file, with the error they must be rejected with, if any.
+var craftedArchives = []struct {
+	name    string
+	entries []testEntry
+	err     string
+}{
+	{"valid", []testEntry{{"go/", fs.ModeDir | 0755, "", ""}, {"go/bin/", fs.ModeDir | 0755, "", ""}, {"go/bin/go", 0755, "go", ""}, {"go/VERSION", 0644, "go1.99", ""}}, ""},
+	{"no directory entries", []testEntry{{"go/src/cmd/go/main.go", 0644, "package main", ""}}, ""},
+	{"absolute", []testEntry{{"/tmp/evil", 0644, "evil", ""}}, "absolute"},
+	{"dot dot", []testEntry{{"go/../evil", 0644, "evil", ""}}, "not a clean path"},
+	{"nested dot dot", []testEntry{{"go/src/../../evil", 0644, "evil", ""}}, "not a clean path"},
+	{"dot", []testEntry{{"go/./VERSION", 0644, "go1.99", ""}}, "not a clean path"},
+	{"empty element", []testEntry{{"go//VERSION", 0644, "go1.99", ""}}, "not a clean path"},
+	{"backslash", []testEntry{{`go\..\evil`, 0644, "evil", ""}}, "backslash"},
+	{"newline", []testEntry{{"go/VERSION\n0644 ../evil", 0644, "evil", ""}}, "control character"},
+	{"outside root", []testEntry{{"evil", 0644, "evil", ""}}, "outside go/"},
+	{"prefix of root", []testEntry{{"gopher/evil", 0644, "evil", ""}}, "outside go/"},
+	{"root file", []testEntry{{"go", 0644, "evil", ""}}, "not a directory"},
+	{"duplicate", []testEntry{{"go/VERSION", 0644, "go1.99", ""}, {"go/VERSION", 0644, "go1.98", ""}}, "duplicate"},
+	{"duplicate directory", []testEntry{{"go/src/", fs.ModeDir | 0755, "", ""}, {"go/src", fs.ModeDir | 0755, "", ""}}, "duplicate"},
+	{"setuid", []testEntry{{"go/bin/go", fs.ModeSetuid | 0755, "go", ""}}, "unsupported mode"},
+	{"setgid", []testEntry{{"go/bin/go", fs.ModeSetgid | 0755, "go", ""}}, "unsupported mode"},
+	{"device", []testEntry{{"go/dev", fs.ModeDevice | 0644, "", ""}}, "unsupported mode"},
+	{"char device", []testEntry{{"go/tty", fs.ModeDevice | fs.ModeCharDevice | 0644, "", ""}}, "unsupported mode"},
+	{"fifo", []testEntry{{"go/fifo", fs.ModeNamedPipe | 0644, "", ""}}, "unsupported mode"},
+	{"symlinks", []testEntry{
+		{"go/pkg/tool/gofmt", 0755, "gofmt", ""},
+		{"go/bin/gofmt", fs.ModeSymlink | 0777, "", "../pkg/tool/gofmt"},
+		{"go/tool", fs.ModeSymlink | 0777, "", "pkg/tool"},
+		{"go/lib/time", fs.ModeSymlink | 0777, "", "zoneinfo"}, // dangling
+	}, ""},
+	{"symlink to absolute path", []testEntry{{"go/etc", fs.ModeSymlink | 0777, "", "/etc"}}, "absolute path"},
+	{"symlink outside", []testEntry{{"go/bin/etc", fs.ModeSymlink | 0777, "", "../../etc"}}, "resolve inside go/"},
+	{"symlink through symlink", []testEntry{
+		{"go/self", fs.ModeSymlink | 0777, "", "."},
+		{"go/up", fs.ModeSymlink | 0777, "", "self/.."},
+	}, "resolve inside go/"},
+	{"symlink through later symlink", []testEntry{
+		{"go/up", fs.ModeSymlink | 0777, "", "self/.."},
+		{"go/self", fs.ModeSymlink | 0777, "", "."},
+	}, "resolve inside go/"},
+	{"symlink loop", []testEntry{
+		{"go/a", fs.ModeSymlink | 0777, "", "b"},
+		{"go/b", fs.ModeSymlink | 0777, "", "a"},
+	}, "resolve inside go/"},
+	{"file through symlink", []testEntry{
+		{"go/src", fs.ModeSymlink | 0777, "", "lib"},
+		{"go/src/evil", 0644, "evil", ""},
+	}, "inside symbolic link"},
+	{"symlink over directory", []testEntry{
+		{"go/src/go.mod", 0644, "module std", ""},
+		{"go/src", fs.ModeSymlink | 0777, "", "lib"},
+	}, "replace a directory"},
+	{"hard link", []testEntry{
+		{"go/bin/go", 0755, "go", ""},
+		{"go/pkg/tool/go", 0755, "", "go/bin/go"},
+	}, ""},
+	{"hard link outside", []testEntry{{"go/passwd", 0644, "", "etc/passwd"}}, "outside go/"},
+	{"hard link to absolute path", []testEntry{{"go/passwd", 0644, "", "/etc/passwd"}}, "absolute path"},
+	{"hard link to dot dot", []testEntry{{"go/passwd", 0644, "", "go/../passwd"}}, "not a clean path"},
+	{"hard link to later file", []testEntry{
+		{"go/bin/gofmt", 0755, "", "go/pkg/tool/gofmt"},
+		{"go/pkg/tool/gofmt", 0755, "gofmt", ""},
+	}, "not an earlier file"},
+	{"hard link to symlink", []testEntry{
+		{"go/lib", fs.ModeSymlink | 0777, "", "src"},
+		{"go/lib2", 0644, "", "go/lib"},
+	}, "not an earlier file"},
+}
+
+// craftTarGz returns a tar.gz archive of entries.
+func craftTarGz(t testing.TB, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: int64(e.mode.Perm()), Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.mode&fs.ModeSymlink != 0:
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, e.link
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeLink, e.link
+		case e.mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+		case e.mode&fs.ModeCharDevice != 0:
+			hdr.Typeflag = tar.TypeChar
+		case e.mode&fs.ModeDevice != 0:
+			hdr.Typeflag = tar.TypeBlock
+		case e.mode&fs.ModeNamedPipe != 0:
+			hdr.Typeflag = tar.TypeFifo
+		}
+		if e.mode&fs.ModeSetuid != 0 {
+			hdr.Mode |= 04000
+		}
+		if e.mode&fs.ModeSetgid != 0 {
+			hdr.Mode |= 02000
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.body))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// craftZip returns a zip archive of entries, or nil if they include hard
+// links, which zip files can't hold.
+func craftZip(t testing.TB, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		body := e.body
+		if e.mode&fs.ModeSymlink != 0 {
+			body = e.link
+		} else if e.link != "" {
+			return nil
+		}
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		hdr.SetMode(e.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// unpackTestTarGz and unpackTestZip unpack archive to root/go.
+func unpackTestTarGz(root string, archive []byte) error {
+	return unpackTarReader(filepath.Join(root, "go"), bytes.NewReader(archive), nil)
+}
+
+func unpackTestZip(root string, archive []byte) error {
+	file := filepath.Join(root, "archive.zip")
+	if err := os.WriteFile(file, archive, 0644); err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return unpackZip(filepath.Join(root, "go"), file, "go/", nil)
+}
+
+// checkContained checks that unpacking an archive to root/go created
+// nothing else in root.
+func checkContained(t *testing.T, root string) {
+	t.Helper()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "go" {
+			t.Errorf("unpacking created %s outside of the target directory", e.Name())
+		}
+	}
+}
+
+func TestUnpackCraftedArchives(t *testing.T) {
+	formats := []struct {
+		name   string
+		craft  func(testing.TB, []testEntry) []byte
+		unpack func(string, []byte) error
+	}{
+		{"tar.gz", craftTarGz, unpackTestTarGz},
+		{"zip", craftZip, unpackTestZip},
+	}
+	for _, format := range formats {
+		for _, tt := range craftedArchives {
+			t.Run(format.name+"/"+tt.name, func(t *testing.T) {
+				archive := format.craft(t, tt.entries)
+				if archive == nil {
+					t.Skipf("%s files can't hold these entries", format.name)
+				}
+				root := t.TempDir()
+				err := format.unpack(root, archive)
+				if tt.err == "" && err != nil {
+					t.Errorf("unpack failed: %v", err)
+				} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+					t.Errorf("unpack = %v; want error containing %q", err, tt.err)
+				}
+				checkContained(t, root)
+			})
+		}
+	}
+}
+
+func TestUnpackLinks(t *testing.T) {
+	entries := []testEntry{
+		{"go/bin/go", 0755, "go", ""},
+		{"go/pkg/tool/go", 0755, "", "go/bin/go"},
+		{"go/bin/gofmt", fs.ModeSymlink | 0777, "", "../pkg/tool/gofmt"},
+	}
+	root := t.TempDir()
+	if err := unpackTestTarGz(root, craftTarGz(t, entries)); err != nil {
+		t.Fatal(err)
+	}
+	target, err := os.Readlink(filepath.Join(root, "go", "bin", "gofmt"))
+	if want := filepath.FromSlash("../pkg/tool/gofmt"); err != nil || target != want {
+		t.Errorf("symbolic link to %q, %v; want %q", target, err, want)
+	}
+	fi1, err1 := os.Stat(filepath.Join(root, "go", "bin", "go"))
+	fi2, err2 := os.Stat(filepath.Join(root, "go", "pkg", "tool", "go"))
+	if err1 != nil || err2 != nil || !os.SameFile(fi1, fi2) {
+		t.Errorf("hard link not made: %v, %v", err1, err2)
+	}
+}
+
+func FuzzUnpackTarGz(f *testing.F) {
+	for _, tt := range craftedArchives {
+		f.Add(craftTarGz(f, tt.entries))
+	}
+	f.Fuzz(func(t *testing.T, archive []byte) {
+		root := t.TempDir()
+		unpackTestTarGz(root, archive)
+		checkContained(t, root)
+	})
+}
+
+func FuzzUnpackZip(f *testing.F) {
+	for _, tt := range craftedArchives {
+		if archive := craftZip(f, tt.entries); archive != nil {
+			f.Add(archive)
+		}
+	}
+	f.Fuzz(func(t *testing.T, archive []byte) {
+		root := t.TempDir()
+		unpackTestZip(root, archive)
+		checkContained(t, root)
+	})
+}
@@ -0,0 +1,215 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// verifyFlags parses the arguments of the verify command, args, and
+// reports whether the -repair flag was given.
+func verifyFlags(version string, args []string) (repair bool) {
+	fs := flag.NewFlagSet(version+" verify", flag.ExitOnError)
+	fs.BoolVar(&repair, "repair", false, "restore modified and missing files from the cached archive, and remove extra files")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return repair
+}
+
+// verify checks the installed GOROOT root of version against its manifest,
+// printing the files that differ from it, and restores them if repair is
+// set.
+func verify(root, version string, repair bool) error {
+	if _, err := os.Stat(filepath.Join(root, unpackedOkay)); err != nil {
+		return fmt.Errorf("not downloaded. Run '%s download' to install to %v", version, root)
+	}
+	m, err := readManifest(root)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s has no manifest to verify it against; it was installed by an older wrapper", root)
+	}
+	if err != nil {
+		return err
+	}
+	r, err := verifyGoroot(root, m)
+	if err != nil {
+		return err
+	}
+	r.print()
+	if r.ok() {
+		log.Printf("%s: %d files in %v verified", version, len(m.files), root)
+		return nil
+	}
+	if !repair {
+		return fmt.Errorf("%v differs from its manifest. Run '%s verify -repair' to restore it", root, version)
+	}
+	if err := repairGoroot(root, m, r); err != nil {
+		return fmt.Errorf("repairing %v: %v", root, err)
+	}
+	if r, err = verifyGoroot(root, m); err != nil {
+		return err
+	}
+	if !r.ok() {
+		r.print()
+		return fmt.Errorf("%v still differs from its manifest after repair", root)
+	}
+	log.Printf("%s: repaired %v", version, root)
+	return nil
+}
+
+// A verifyReport lists the files of an installed GOROOT that differ from
+// its manifest, by their slash-separated paths relative to it, in order.
+type verifyReport struct {
+	modified []string // differ in content, permissions or type
+	missing  []string // listed in the manifest, but not installed
+	extra    []string // installed, but not listed in the manifest
+}
+
+func (r *verifyReport) ok() bool {
+	return len(r.modified) == 0 && len(r.missing) == 0 && len(r.extra) == 0
+}
+
+func (r *verifyReport) print() {
+	for _, rel := range r.modified {
+		fmt.Printf("modified %s\n", rel)
+	}
+	for _, rel := range r.missing {
+		fmt.Printf("missing %s\n", rel)
+	}
+	for _, rel := range r.extra {
+		fmt.Printf("extra %s\n", rel)
+	}
+}
+
+// verifyGoroot compares the files in root with its manifest m. A directory
+// in place of a file is reported as modified, but not its contents.
+func verifyGoroot(root string, m *manifest) (*verifyReport, error) {
+	r := new(verifyReport)
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file == root {
+			return nil
+		}
+		rel := filepath.ToSlash(strings.TrimPrefix(file, root+string(filepath.Separator)))
+		want, ok := m.files[rel]
+		seen[rel] = ok
+		switch {
+		case d.IsDir():
+			if ok {
+				r.modified = append(r.modified, rel)
+				return filepath.SkipDir
+			}
+			return nil
+		case !ok:
+			if !isInstallFile(rel) {
+				r.extra = append(r.extra, rel)
+			}
+			return nil
+		}
+		got, err := hashEntry(file, d)
+		if err != nil || got != want {
+			r.modified = append(r.modified, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for rel := range m.files {
+		if !seen[rel] {
+			r.missing = append(r.missing, rel)
+		}
+	}
+	sort.Strings(r.missing)
+	return r, nil
+}
+
+// repairGoroot restores the modified and missing files of the GOROOT root
+// reported by r from the archive named by its manifest m, which must be
+// kept in root or in the download cache, and removes its extra files.
+func repairGoroot(root string, m *manifest, r *verifyReport) (err error) {
+	if m.archive == "" {
+		return errors.New("no archive was recorded to restore files from; remove it and download it again")
+	}
+	archive, err := findArchive(root, m)
+	if err != nil {
+		return err
+	}
+	// Unpack the archive next to root, so that files can be moved from it.
+	tmp := root + ".repair"
+	if err := removeAll(tmp); err != nil {
+		return err
+	}
+	defer removeAll(tmp)
+	if err := unpackVerifiedArchive(tmp, archive, nil); err != nil {
+		return err
+	}
+	if isReadOnly(root) {
+		if err := makeWritable(root); err != nil {
+			return err
+		}
+		// The restored files are made read-only again too.
+		defer func() {
+			if roErr := makeReadOnly(root); roErr != nil && err == nil {
+				err = roErr
+			}
+		}()
+	}
+	for _, rel := range r.extra {
+		if err := os.Remove(filepath.Join(root, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+	}
+	for _, rel := range append(r.modified, r.missing...) {
+		file := filepath.Join(root, filepath.FromSlash(rel))
+		if err := removeAll(file); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(tmp, filepath.FromSlash(rel)), file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findArchive returns the name of the archive named by the manifest m of
+// the GOROOT root, kept in root or in the download cache, after verifying
+// its SHA-256.
+func findArchive(root string, m *manifest) (string, error) {
+	candidates := []string{filepath.Join(root, m.archive)}
+	if cached := openArchiveCache().lookup(m.sha256, m.archive); cached != "" {
+		candidates = append(candidates, cached)
+	}
+	for _, file := range candidates {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		sum, err := fileSHA256(file)
+		if err != nil {
+			return "", err
+		}
+		if sum == m.sha256 {
+			return file, nil
+		}
+		log.Printf("%s corrupt? does not have expected SHA-256 of %v", file, m.sha256)
+	}
+	return "", fmt.Errorf("%s is neither kept in %s nor in the download cache; remove %s and download it again", m.archive, root, root)
+}
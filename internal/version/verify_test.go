@@ -0,0 +1,173 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// installTestGoroot installs a go1.99 release with a few files from a test
+// mirror, returning its GOROOT.
+func installTestGoroot(t *testing.T) string {
+	t.Helper()
+	archive := testArchive(t, map[string]string{
+		"VERSION":          "go1.99",
+		"bin/go":           "#!/bin/sh\n",
+		"src/fmt/print.go": "package fmt\n",
+		"src/fmt/scan.go":  "package fmt\n",
+	})
+	mirror := httptest.NewServer(serveArchive(archive, true))
+	t.Cleanup(mirror.Close)
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+	root := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(root, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestManifest(t *testing.T) {
+	root := installTestGoroot(t)
+	m, err := readManifest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := versionArchiveName("go1.99"); m.archive != name {
+		t.Errorf("manifest archive = %q; want %q", m.archive, name)
+	}
+	var rels []string
+	for rel := range m.files {
+		rels = append(rels, rel)
+	}
+	if len(rels) != 4 {
+		t.Errorf("manifest lists %q; want the 4 unpacked files", rels)
+	}
+	want := fmt.Sprintf("%x", sha256.Sum256([]byte("package fmt\n")))
+	if e := m.files["src/fmt/print.go"]; e.sha256 != want {
+		t.Errorf("SHA-256 of src/fmt/print.go = %q; want %q", e.sha256, want)
+	}
+	r, err := verifyGoroot(root, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.ok() {
+		t.Errorf("verifying fresh install reported %+v", r)
+	}
+}
+
+func TestReadManifestPaths(t *testing.T) {
+	sum := fmt.Sprintf("%x", sha256.Sum256(nil))
+	for _, rel := range []string{"src/fmt/print.go", "../../.ssh/x", "src/../../x", "/etc/passwd", "src//x", "./x", `src\x`} {
+		dir := t.TempDir()
+		data := "archive  \n" + sum + " 0644 " + rel + "\n"
+		if err := os.WriteFile(filepath.Join(dir, manifestFile), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readManifest(dir)
+		if ok := rel == "src/fmt/print.go"; (err == nil) != ok {
+			t.Errorf("readManifest with path %q = %v; want ok %v", rel, err, ok)
+		}
+	}
+	for _, archive := range []string{"../go1.99.linux-amd64.tar.gz", "/tmp/go.tar.gz"} {
+		dir := t.TempDir()
+		data := "archive " + archive + " " + sum + "\n"
+		if err := os.WriteFile(filepath.Join(dir, manifestFile), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readManifest(dir); err == nil {
+			t.Errorf("readManifest with archive %q succeeded", archive)
+		}
+	}
+}
+
+func TestVerifyRepair(t *testing.T) {
+	// The files are modified in place.
+	t.Setenv("GODL_READONLY", "0")
+	for _, keep := range []bool{true, false} {
+		t.Run(fmt.Sprintf("keep=%v", keep), func(t *testing.T) {
+			if !keep {
+				// Repair from the download cache instead.
+				t.Setenv("GODL_KEEP_ARCHIVE", "0")
+				t.Setenv("GODL_CACHE_MAX_SIZE", "")
+				t.Setenv("GODL_CACHE", t.TempDir())
+			}
+			root := installTestGoroot(t)
+			if err := os.WriteFile(filepath.Join(root, "src", "fmt", "print.go"), []byte("package fmt // edited\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(filepath.Join(root, "src", "fmt", "scan.go")); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "src", "fmt", "extra.go"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(filepath.Join(root, "VERSION")); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(root, "VERSION", "dir"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			want := &verifyReport{
+				modified: []string{"VERSION", "src/fmt/print.go"},
+				missing:  []string{"src/fmt/scan.go"},
+				extra:    []string{"src/fmt/extra.go"},
+			}
+			if runtime.GOOS != "windows" {
+				if err := os.Chmod(filepath.Join(root, "bin", "go"), 0700); err != nil {
+					t.Fatal(err)
+				}
+				want.modified = []string{"VERSION", "bin/go", "src/fmt/print.go"}
+			}
+			m, err := readManifest(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := verifyGoroot(root, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r, want) {
+				t.Errorf("verifyGoroot = %+v; want %+v", r, want)
+			}
+			if err := verify(root, "go1.99", false); err == nil || !strings.Contains(err.Error(), "-repair") {
+				t.Errorf("verify = %v; want error suggesting -repair", err)
+			}
+
+			if err := verify(root, "go1.99", true); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := os.ReadFile(filepath.Join(root, "src", "fmt", "print.go")); err != nil || string(got) != "package fmt\n" {
+				t.Errorf("repaired print.go = %q, %v", got, err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "src", "fmt", "extra.go")); !os.IsNotExist(err) {
+				t.Errorf("extra file not removed: %v", err)
+			}
+			if _, err := os.Stat(root + ".repair"); !os.IsNotExist(err) {
+				t.Errorf("repair directory not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyNoArchive(t *testing.T) {
+	t.Setenv("GODL_READONLY", "0")
+	t.Setenv("GODL_KEEP_ARCHIVE", "0")
+	root := installTestGoroot(t)
+	if err := os.Remove(filepath.Join(root, "VERSION")); err != nil {
+		t.Fatal(err)
+	}
+	err := verify(root, "go1.99", true)
+	if err == nil || !strings.Contains(err.Error(), "download it again") {
+		t.Errorf("verify -repair without archive = %v; want error", err)
+	}
+}
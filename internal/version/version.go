@@ -8,11 +8,15 @@ package version
 import (
 	"archive/tar"
 	"archive/zip"
-	"compress/gzip"
+	"bufio"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -27,10 +31,6 @@ import (
 	"time"
 )
 
-func init() {
-	http.DefaultTransport = &userAgentTransport{http.DefaultTransport}
-}
-
 // Run runs the "go" tool of the provided Go version.
 func Run(version string) {
 	log.SetFlags(0)
@@ -40,13 +40,47 @@ func Run(version string) {
 		log.Fatalf("%s: %v", version, err)
 	}
 
-	if len(os.Args) == 2 && os.Args[1] == "download" {
-		if err := install(root, version); err != nil {
+	if len(os.Args) >= 2 && os.Args[1] == "download" {
+		opts := downloadFlags(version, os.Args[2:])
+		if opts.archive != "" {
+			err = installLocal(root, version, opts.archive)
+		} else {
+			err = install(opts.platform.sdkDir(root), version, opts.platform)
+		}
+		if err != nil {
 			log.Fatalf("%s: download failed: %v", version, err)
 		}
 		os.Exit(0)
 	}
 
+	if len(os.Args) >= 2 && os.Args[1] == "verify" {
+		repair := verifyFlags(version, os.Args[2:])
+		if err := verify(root, version, repair); err != nil {
+			log.Fatalf("%s: verify failed: %v", version, err)
+		}
+		os.Exit(0)
+	}
+
+	if len(os.Args) >= 2 && os.Args[1] == "uninstall" {
+		uninstallFlags(version, os.Args[2:])
+		if err := removeAll(root); err != nil {
+			log.Fatalf("%s: uninstall failed: %v", version, err)
+		}
+		log.Printf("Removed %v", root)
+		os.Exit(0)
+	}
+
+	if len(os.Args) >= 2 && os.Args[1] == "dedupe" {
+		dedupeFlags(version, os.Args[2:])
+		sdkDir := filepath.Dir(root)
+		stats, err := dedupe(sdkDir, "")
+		if err != nil {
+			log.Fatalf("%s: dedupe failed: %v", version, err)
+		}
+		stats.log(sdkDir)
+		os.Exit(0)
+	}
+
 	if _, err := os.Stat(filepath.Join(root, unpackedOkay)); err != nil {
 		log.Fatalf("%s: not downloaded. Run '%s download' to install to %v", version, version, root)
 	}
@@ -54,6 +88,66 @@ func Run(version string) {
 	runGo(root, "")
 }
 
+// downloadOptions are the options of the download command.
+type downloadOptions struct {
+	platform platform // platform to download Go for
+	archive  string   // local archive to install instead
+}
+
+// downloadFlags parses the arguments of the download command, args. The
+// -os and -arch flags select another platform to download Go for than the
+// host, defaulting to $GODL_GOOS and $GODL_GOARCH; the architecture may
+// also be given as "armv6l", as in archive names. The -archive flag names
+// a release archive to install instead of downloading one.
+func downloadFlags(version string, args []string) downloadOptions {
+	opts := downloadOptions{platform: hostPlatform()}
+	p := &opts.platform
+	if goos := os.Getenv("GODL_GOOS"); goos != "" {
+		p.goos = goos
+	}
+	if goarch := os.Getenv("GODL_GOARCH"); goarch != "" {
+		p.goarch = goarch
+	}
+	fs := flag.NewFlagSet(version+" download", flag.ExitOnError)
+	fs.StringVar(&p.goos, "os", p.goos, "download Go for the given `GOOS`")
+	fs.StringVar(&p.goarch, "arch", p.goarch, "download Go for the given `GOARCH`")
+	fs.StringVar(&opts.archive, "archive", "", "install the release archive in `file`, for the platform in its name")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if p.goarch == "armv6l" {
+		p.goarch = "arm"
+	}
+	return opts
+}
+
+// RunWithChecksums is like Run, but verifies the release archives it
+// downloads against sums, which maps their file names, such as
+// "go1.22.3.linux-amd64.tar.gz", to their hex-encoded SHA-256. A wrapper
+// command compiles in the sums of its version, generated from the release
+// index by internal/gensums, so that a download is only trusted if it
+// matches the wrapper module verified by go.sum. Archives missing from
+// sums, and the golang.org/toolchain module, are refused.
+func RunWithChecksums(version string, sums map[string]string) {
+	compiledSHA256 = sums
+	Run(version)
+}
+
+// compiledSHA256 holds the SHA-256 of the release archives compiled into
+// the wrapper command, as passed to RunWithChecksums.
+var compiledSHA256 map[string]string
+
+// errCompiledArchive is reported when an archive doesn't match the SHA-256
+// compiled into the wrapper command.
+var errCompiledArchive = errors.New("archive doesn't match checksum compiled into wrapper")
+
+// errNoChecksum is reported when there is no trusted SHA-256 to verify an
+// archive against, such as when the wrapper has checksums compiled in, but
+// not for the archive.
+var errNoChecksum = errors.New("no trusted checksum")
+
 func runGo(root, gotoolchain string) {
 	gobin := filepath.Join(root, "bin", "go"+exe())
 	cmd := exec.Command(gobin, os.Args[1:]...)
@@ -107,90 +201,483 @@ func fmtSize(size int64) string {
 	return fmt.Sprintf("%s %s", formatted, unit)
 }
 
-// install installs a version of Go to the named target directory, creating the
-// directory as needed.
-func install(targetDir, version string) error {
+// install installs a version of Go for platform p to the named target
+// directory, creating the directory as needed.
+func install(targetDir, version string, p platform) error {
 	if _, err := os.Stat(filepath.Join(targetDir, unpackedOkay)); err == nil {
 		log.Printf("%s: already downloaded in %v", version, targetDir)
 		return nil
 	}
 
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
+	err := installStaged(targetDir, func(dir string) error {
+		mirrors := mirrorURLs()
+		var err error
+		for i, baseURL := range mirrors {
+			if baseURL == proxyMirror {
+				err = installFromProxy(dir, version, p)
+			} else {
+				err = installFrom(dir, version, p, baseURL)
+			}
+			if err == nil || i == len(mirrors)-1 || !tryNextMirror(err) {
+				break
+			}
+			log.Printf("%s: %v; trying next mirror", version, err)
+		}
+		var noRelease *noReleaseError
+		if errors.As(err, &noRelease) && p == hostPlatform() && buildSource() {
+			err = installFromSource(dir, targetDir, version, mirrors, noRelease)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	dedupeInstalled(targetDir)
+	logInstalled(targetDir, version, p)
+	return nil
+}
+
+// installStaged calls install to install Go to a staging directory next to
+// targetDir, and then renames it to targetDir, so that targetDir is either
+// missing or complete. Downloads left in the staging directory by a failed
+// install are kept so that they can be resumed, but anything else is
+// removed first, so that no stale files end up in targetDir.
+func installStaged(targetDir string, install func(dir string) error) error {
+	staging := targetDir + stagingSuffix
+	if err := cleanStaging(staging); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return err
+	}
+	if err := install(staging); err != nil {
 		return err
 	}
-	goURL := versionArchiveURL(version)
-	res, err := http.Head(goURL)
+	if _, err := os.Stat(filepath.Join(staging, unpackedOkay)); err != nil {
+		return fmt.Errorf("install to %v did not complete: %v", staging, err)
+	}
+	if readOnly() {
+		if err := makeReadOnly(staging); err != nil {
+			return err
+		}
+	}
+	// targetDir may hold an incomplete install by an older version of
+	// this program, or one whose sentinel was removed to reinstall it.
+	if err := removeAll(targetDir); err != nil {
+		return err
+	}
+	return os.Rename(staging, targetDir)
+}
+
+// cleanStaging removes everything but partial and complete downloads of
+// archives from the staging directory.
+func cleanStaging(staging string) error {
+	entries, err := os.ReadDir(staging)
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	if res.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("no binary release of %v for %v/%v at %v", version, getOS(), runtime.GOARCH, goURL)
+	// An install that failed to move the staging directory into place
+	// may have made it read-only.
+	if isReadOnly(staging) {
+		if err := makeWritable(staging); err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() && isDownloadFile(name) {
+			continue
+		}
+		if err := removeAll(filepath.Join(staging, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDownloadFile reports whether name is that of a downloaded archive, or
+// of the partial download of one or its validator.
+func isDownloadFile(name string) bool {
+	download := strings.TrimSuffix(strings.TrimSuffix(name, validatorSuffix), partialSuffix)
+	return archiveExt(download) != ""
+}
+
+// logInstalled reports that version was installed for platform p to
+// targetDir.
+func logInstalled(targetDir, version string, p platform) {
+	if p != hostPlatform() {
+		log.Printf("Success. Installed %v for %v to %v", version, p, targetDir)
+		return
+	}
+	log.Printf("Success. You may now run '%v'", version)
+}
+
+// installFrom is the implementation of install that downloads the archive
+// from the mirror at baseURL.
+func installFrom(targetDir, version string, p platform, baseURL string) error {
+	name := p.archiveName(version)
+	if _, ok := compiledSHA256[name]; !ok && compiledSHA256 != nil {
+		// The wrapper knows every archive of its release.
+		return &noReleaseError{version: version, platform: p, url: baseURL + name}
 	}
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned %v checking size of %v", http.StatusText(res.StatusCode), goURL)
+	err := fetchArchive(targetDir, version, p, baseURL+name)
+	// Mirrors may serve the archive recompressed, under the name of its
+	// format, which only their own .sha256 file can verify.
+	stem := strings.TrimSuffix(name, archiveExt(name))
+	for _, ext := range recompressedExts {
+		var nre *noReleaseError
+		if !errors.As(err, &nre) || compiledSHA256 != nil || verifyWithIndex() {
+			break
+		}
+		if err := formatOfName(ext).checkCommand(); err != nil {
+			log.Printf("not looking for %s: %v", stem+ext, err)
+			continue
+		}
+		err = fetchArchive(targetDir, version, p, baseURL+stem+ext)
+		if errors.As(err, new(*noReleaseError)) || errors.Is(err, errNoChecksum) {
+			// Report the release name.
+			err = nre
+		}
 	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(targetDir, unpackedOkay), nil, 0644)
+}
+
+// fetchArchive downloads the archive of version at goURL, verifies it and
+// unpacks it to targetDir, with a manifest of the unpacked files. If there
+// is no such archive, it returns a noReleaseError for platform p.
+func fetchArchive(targetDir, version string, p platform, goURL string) (err error) {
 	base := path.Base(goURL)
+	if err := formatOfName(base).checkCommand(); err != nil {
+		return err
+	}
+	var indexed *releaseFile
+	// The SHA-256 compiled into the wrapper takes precedence over any that
+	// is downloaded. The wrapper knows every archive of its release, so
+	// any other is refused rather than verified against the mirror.
+	wantSHA, compiled := compiledSHA256[base]
+	if !compiled && compiledSHA256 != nil {
+		return fmt.Errorf("%w for %s compiled into wrapper", errNoChecksum, base)
+	}
+	cache := openArchiveCache()
+	if !compiled {
+		if verifyWithIndex() {
+			if indexed, err = lookupReleaseIndex(base); err != nil {
+				return err
+			}
+			if indexed == nil {
+				// Such as the binary releases of old versions for some
+				// platforms, which may still be built from source.
+				return &noReleaseError{version: version, platform: p, url: releaseIndexURL}
+			}
+		} else {
+			// An archive verified before is reinstalled from the cache
+			// without going to the network.
+			wantSHA = cache.sha(base)
+		}
+		if wantSHA == "" {
+			wantSHA, err = expectedSHA256(goURL, indexed)
+			if err != nil {
+				var se *statusError
+				if errors.As(err, &se) && se.statusCode == http.StatusNotFound {
+					return &noReleaseError{version: version, platform: p, url: checksumURL(goURL)}
+				}
+				return err
+			}
+		}
+	}
+	checkSHA256 := func(file, gotSHA string) error {
+		if gotSHA == wantSHA {
+			return nil
+		}
+		// Don't let the corrupt archive be mistaken for a complete
+		// download next time.
+		os.Remove(file)
+		err := fmt.Errorf("%s corrupt? does not have expected SHA-256 of %v", file, wantSHA)
+		switch {
+		case compiled:
+			return fmt.Errorf("%w: %v", errCompiledArchive, err)
+		case indexed != nil:
+			return fmt.Errorf("%w: %v", errIndexArchive, err)
+		}
+		return fmt.Errorf("error verifying SHA256 of %v: %v", file, err)
+	}
+	m := newManifest(base, wantSHA)
+	defer func() {
+		if err == nil {
+			err = m.write(targetDir)
+		}
+	}()
+
 	archiveFile := filepath.Join(targetDir, base)
-	if fi, err := os.Stat(archiveFile); err != nil || fi.Size() != res.ContentLength {
-		if err != nil && !os.IsNotExist(err) {
-			// Something weird. Don't try to download.
+	keep := keepArchive()
+	if !keep {
+		defer os.Remove(archiveFile)
+	}
+	if cached := cache.lookup(wantSHA, base); cached != "" {
+		gotSHA, err := fileSHA256(cached)
+		if err != nil {
+			return err
+		}
+		if err := checkSHA256(cached, gotSHA); err != nil {
+			return err
+		}
+		log.Printf("Using %v from the download cache", base)
+		if !keep {
+			return unpackVerifiedArchive(targetDir, cached, m)
+		}
+		if err := linkOrCopy(cached, archiveFile); err != nil {
+			return err
+		}
+		return unpackVerifiedArchive(targetDir, archiveFile, m)
+	}
+
+	var res *http.Response
+	c, err := httpClient()
+	if err != nil {
+		return err
+	}
+	err = retry("checking size of "+goURL, func() (err error) {
+		res, err = c.Head(goURL)
+		if err != nil {
 			return err
 		}
-		if err := copyFromURL(archiveFile, goURL); err != nil {
-			return fmt.Errorf("error downloading %v: %v", goURL, err)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+			return newStatusError(res, fmt.Errorf("server returned %v", http.StatusText(res.StatusCode)))
 		}
-		fi, err = os.Stat(archiveFile)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return &noReleaseError{version: version, platform: p, url: goURL}
+	}
+	if indexed != nil && indexed.Size != res.ContentLength {
+		return fmt.Errorf("%w: %v has %d bytes; index has %d", errIndexSize, goURL, res.ContentLength, indexed.Size)
+	}
+
+	fi, err := os.Stat(archiveFile)
+	if err != nil && !os.IsNotExist(err) {
+		// Something weird. Don't try to download.
+		return err
+	}
+	if err == nil && fi.Size() == res.ContentLength {
+		gotSHA, err := fileSHA256(archiveFile)
 		if err != nil {
 			return err
 		}
-		if fi.Size() != res.ContentLength {
-			return fmt.Errorf("downloaded file %s size %v doesn't match server size %v", archiveFile, fi.Size(), res.ContentLength)
+		if err := checkSHA256(archiveFile, gotSHA); err != nil {
+			return err
 		}
+		cache.add(archiveFile, wantSHA, base, false)
+		return unpackVerifiedArchive(targetDir, archiveFile, m)
+	}
+
+	conns := downloadConnections()
+	validator := responseValidator(res)
+	if conns > 1 && res.ContentLength >= conns*minChunkSize && validator != "" {
+		if err := copyFromURLParallel(archiveFile, goURL, res.ContentLength, validator, conns); err != nil {
+			return fmt.Errorf("error downloading %v: %w", goURL, err)
+		}
+		gotSHA, err := fileSHA256(archiveFile)
+		if err != nil {
+			return err
+		}
+		if err := checkSHA256(archiveFile, gotSHA); err != nil {
+			return err
+		}
+		cache.add(archiveFile, wantSHA, base, false)
+		return unpackVerifiedArchive(targetDir, archiveFile, m)
+	}
+
+	if f := formatOfName(base); f == nil || !f.stream {
+		var gotSHA string
+		err := retry("downloading "+goURL, func() (err error) {
+			gotSHA, err = copyFromURL(archiveFile, goURL, nil)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error downloading %v: %w", goURL, err)
+		}
+		if err := checkSHA256(archiveFile, gotSHA); err != nil {
+			return err
+		}
+		cache.add(archiveFile, wantSHA, base, false)
+		return unpackVerifiedArchive(targetDir, archiveFile, m)
+	}
+
+	// Extract the tar archive as it downloads. targetDir is a staging directory,
+	// which is only moved into place once the SHA-256 of the archive is
+	// verified.
+	//
+	// Without a kept archive, download to the cache directory instead, so
+	// the download can still be resumed and then added to the cache.
+	dst := archiveFile
+	if !keep && cache != nil {
+		dst = cache.tempFile(base)
+	}
+	log.Printf("Downloading and unpacking %v ...", goURL)
+	pr, pw := io.Pipe()
+	unpacked := make(chan error, 1)
+	go func() {
+		err := unpackTarStream(targetDir, pr, m)
+		if err == nil || err == errVerifyFirst {
+			// Consume the rest of the compressed stream.
+			if _, copyErr := io.Copy(io.Discard, pr); copyErr != nil {
+				err = copyErr
+			}
+		}
+		pr.CloseWithError(err)
+		unpacked <- err
+	}()
+	// A retried download starts over, or resumes from the partial file,
+	// which is read back. Either way, don't unpack the same bytes twice,
+	// and fail if they changed, so that what is unpacked is what the
+	// SHA-256 verifies, including anything after the end of the tar.
+	tee := &replayWriter{w: pw}
+	var gotSHA string
+	err = retry("downloading "+goURL, func() (err error) {
+		tee.restart()
+		if gotSHA, err = copyFromURL(dst, goURL, tee); err != nil {
+			return err
+		}
+		return tee.check()
+	})
+	pw.CloseWithError(err)
+	unpackErr := <-unpacked
+	if unpackErr != nil && unpackErr != errVerifyFirst && err == nil {
+		return fmt.Errorf("extracting archive %v: %v", archiveFile, unpackErr)
 	}
-	wantSHA, err := slurpURLToString(goURL + ".sha256")
 	if err != nil {
+		return fmt.Errorf("error downloading %v: %w", goURL, err)
+	}
+	if err := checkSHA256(dst, gotSHA); err != nil {
 		return err
 	}
-	if err := verifySHA256(archiveFile, strings.TrimSpace(wantSHA)); err != nil {
-		return fmt.Errorf("error verifying SHA256 of %v: %v", archiveFile, err)
+	if unpackErr == errVerifyFirst {
+		// The archive isn't in the format its name says.
+		if err := unpackVerifiedArchive(targetDir, dst, m); err != nil {
+			return err
+		}
+	}
+	if cache != nil {
+		cache.add(dst, wantSHA, base, dst != archiveFile)
 	}
+	return nil
+}
+
+// unpackVerifiedArchive unpacks archiveFile, whose SHA-256 has been
+// verified, to targetDir.
+func unpackVerifiedArchive(targetDir, archiveFile string, m *manifest) error {
 	log.Printf("Unpacking %v ...", archiveFile)
-	if err := unpackArchive(targetDir, archiveFile); err != nil {
+	if err := unpackArchive(targetDir, archiveFile, m); err != nil {
 		return fmt.Errorf("extracting archive %v: %v", archiveFile, err)
 	}
-	if err := os.WriteFile(filepath.Join(targetDir, unpackedOkay), nil, 0644); err != nil {
-		return err
-	}
-	log.Printf("Success. You may now run '%v'", version)
 	return nil
 }
 
-// unpackArchive unpacks the provided archive zip or tar.gz file to targetDir,
-// removing the "go/" prefix from file entries.
-func unpackArchive(targetDir, archiveFile string) error {
-	switch {
-	case strings.HasSuffix(archiveFile, ".zip"):
-		return unpackZip(targetDir, archiveFile)
-	case strings.HasSuffix(archiveFile, ".tar.gz"):
-		return unpackTarGz(targetDir, archiveFile)
-	default:
-		return errors.New("unsupported archive file")
+// keepArchive reports whether to keep the downloaded archive in the
+// installed SDK directory. GODL_KEEP_ARCHIVE=0 discards it.
+func keepArchive() bool {
+	return os.Getenv("GODL_KEEP_ARCHIVE") != "0"
+}
+
+// noReleaseError is returned by installFrom when a mirror has no binary
+// release of a version for a platform.
+type noReleaseError struct {
+	version  string
+	platform platform
+	url      string
+}
+
+func (e *noReleaseError) Error() string {
+	return fmt.Sprintf("no binary release of %v for %v at %v", e.version, e.platform, e.url)
+}
+
+// unpackArchive unpacks the provided archive file to targetDir, removing
+// the "go/" prefix from file entries, and records the files in m. The
+// archive format is detected from the contents of the file rather than its
+// name, since mirrors may serve recompressed archives.
+func unpackArchive(targetDir, archiveFile string, m *manifest) error {
+	f, err := formatOfFile(archiveFile)
+	if err != nil {
+		return err
 	}
+	if f.decompress == nil {
+		return unpackZip(targetDir, archiveFile, "go/", m)
+	}
+	return unpackTar(targetDir, archiveFile, m)
 }
 
-// unpackTarGz is the tar.gz implementation of unpackArchive.
-func unpackTarGz(targetDir, archiveFile string) error {
+// unpackTar is the compressed tar implementation of unpackArchive.
+func unpackTar(targetDir, archiveFile string, m *manifest) error {
 	r, err := os.Open(archiveFile)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
-	madeDir := map[string]bool{}
-	zr, err := gzip.NewReader(r)
+	fi, err := r.Stat()
+	if err != nil {
+		return err
+	}
+	pw := newProgressWriter(io.Discard, phaseUnpacking, 0, fi.Size())
+	if err := unpackTarReader(targetDir, io.TeeReader(r, pw), m); err != nil {
+		return err
+	}
+	pw.done()
+	return nil
+}
+
+// errVerifyFirst is returned by unpackTarStream for an archive in a format
+// that is only unpacked once verified.
+var errVerifyFirst = errors.New("archive must be verified before it is unpacked")
+
+// unpackTarStream is like unpackTarReader, but for an archive that is
+// still downloading, which is refused with errVerifyFirst unless its format
+// may be unpacked before it is verified.
+func unpackTarStream(targetDir string, r io.Reader, m *manifest) error {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(maxMagic)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if f := formatOfMagic(magic); f != nil && !f.stream {
+		return errVerifyFirst
+	}
+	return unpackTarReader(targetDir, br, m)
+}
+
+// unpackTarReader unpacks the compressed tar archive read from r to
+// targetDir, and records the files in m.
+func unpackTarReader(targetDir string, r io.Reader, m *manifest) error {
+	zr, err := decompress(r)
 	if err != nil {
 		return err
 	}
-	tr := tar.NewReader(zr)
+	err = unpackTarEntries(targetDir, tar.NewReader(zr), m)
+	// A decompressor command only reports corrupt input when it exits.
+	if closeErr := zr.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// unpackTarEntries unpacks the entries read from tr to targetDir.
+// Decompression is sequential, but files are written concurrently.
+func unpackTarEntries(targetDir string, tr *tar.Reader, m *manifest) error {
+	pool := newWritePool(unpackWorkers())
+	defer pool.wait()
+	madeDir := map[string]bool{}
+	entries := newEntryValidator("go/")
+	// Hard links are made once the files they link to are written.
+	var hardlinks [][2]string
 	for {
 		f, err := tr.Next()
 		if err == io.EOF {
@@ -199,15 +686,29 @@ func unpackTarGz(targetDir, archiveFile string) error {
 		if err != nil {
 			return err
 		}
-		if !validRelPath(f.Name) {
-			return fmt.Errorf("tar file contained invalid name %q", f.Name)
-		}
-		rel := filepath.FromSlash(strings.TrimPrefix(f.Name, "go/"))
-		abs := filepath.Join(targetDir, rel)
-
 		fi := f.FileInfo()
 		mode := fi.Mode()
+		rel, err := entries.check(f.Name, mode)
+		if err != nil {
+			return err
+		}
+		abs := filepath.Join(targetDir, filepath.FromSlash(rel))
+
 		switch {
+		case f.Typeflag == tar.TypeLink:
+			target, err := entries.checkHardlink(rel, f.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+				return err
+			}
+			hardlinks = append(hardlinks, [2]string{target, rel})
+		case f.Typeflag == tar.TypeSymlink:
+			if err := entries.checkSymlink(rel, f.Linkname); err != nil {
+				return err
+			}
+			m.addSymlink(rel, f.Linkname)
 		case mode.IsRegular():
 			// Make the directory. This is redundant because it should
 			// already be made by a directory entry in the tar
@@ -220,29 +721,29 @@ func unpackTarGz(targetDir, archiveFile string) error {
 				}
 				madeDir[dir] = true
 			}
-			wf, err := os.OpenFile(abs, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode.Perm())
-			if err != nil {
-				return err
-			}
-			n, err := io.Copy(wf, tr)
-			if closeErr := wf.Close(); closeErr != nil && err == nil {
-				err = closeErr
+			if f.Size > maxQueuedFileSize {
+				sum, perm, err := writeFile(abs, mode.Perm(), tr, f.Size, f.ModTime)
+				if err != nil {
+					return err
+				}
+				m.addFile(rel, sum, perm)
+				continue
 			}
+			body, err := io.ReadAll(tr)
 			if err != nil {
 				return fmt.Errorf("error writing to %s: %v", abs, err)
 			}
-			if n != f.Size {
-				return fmt.Errorf("only wrote %d bytes to %s; expected %d", n, abs, f.Size)
-			}
-			if !f.ModTime.IsZero() {
-				if err := os.Chtimes(abs, f.ModTime, f.ModTime); err != nil {
-					// benign error. Gerrit doesn't even set the
-					// modtime in these, and we don't end up relying
-					// on it anywhere (the gomote push command relies
-					// on digests only), so this is a little pointless
-					// for now.
-					log.Printf("error changing modtime: %v", err)
+			size, modTime := f.Size, f.ModTime
+			err = pool.do(func() error {
+				sum, perm, err := writeFile(abs, mode.Perm(), bytes.NewReader(body), size, modTime)
+				if err != nil {
+					return err
 				}
+				m.addFile(rel, sum, perm)
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 		case mode.IsDir():
 			if err := os.MkdirAll(abs, 0755); err != nil {
@@ -253,21 +754,60 @@ func unpackTarGz(targetDir, archiveFile string) error {
 			return fmt.Errorf("tar file entry %s contained unsupported file type %v", f.Name, mode)
 		}
 	}
-	return nil
+	if err := pool.wait(); err != nil {
+		return err
+	}
+	for _, l := range hardlinks {
+		target, rel := l[0], l[1]
+		if err := os.Link(filepath.Join(targetDir, filepath.FromSlash(target)), filepath.Join(targetDir, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+		m.addHardlink(rel, target)
+	}
+	return entries.makeSymlinks(targetDir)
 }
 
-// unpackZip is the zip implementation of unpackArchive.
-func unpackZip(targetDir, archiveFile string) error {
+// unpackZip is the zip implementation of unpackArchive. All entries must
+// be in the prefix directory, which is removed from their names.
+func unpackZip(targetDir, archiveFile, prefix string, m *manifest) error {
 	zr, err := zip.OpenReader(archiveFile)
 	if err != nil {
 		return err
 	}
 	defer zr.Close()
 
-	for _, f := range zr.File {
-		name := strings.TrimPrefix(f.Name, "go/")
-
-		outpath := filepath.Join(targetDir, name)
+	// Check all entries before unpacking any.
+	entries := newEntryValidator(prefix)
+	rels := make([]string, len(zr.File))
+	var size int64
+	for i, f := range zr.File {
+		if rels[i], err = entries.check(f.Name, f.Mode()); err != nil {
+			return err
+		}
+		switch mode := f.Mode(); {
+		case mode&fs.ModeSymlink != 0:
+			// The target of a symbolic link is its content.
+			target, err := readZipSymlink(f)
+			if err != nil {
+				return err
+			}
+			if err := entries.checkSymlink(rels[i], target); err != nil {
+				return err
+			}
+			m.addSymlink(rels[i], target)
+		case !mode.IsRegular() && !mode.IsDir():
+			return fmt.Errorf("zip file entry %s contained unsupported file type %v", f.Name, mode)
+		}
+		size += int64(f.UncompressedSize64)
+	}
+	pw := &lockedWriter{w: newProgressWriter(io.Discard, phaseUnpacking, 0, size)}
+	pool := newWritePool(unpackWorkers())
+	defer pool.wait()
+	for i, f := range zr.File {
+		outpath := filepath.Join(targetDir, filepath.FromSlash(rels[i]))
+		if f.Mode()&fs.ModeSymlink != 0 {
+			continue // made by makeSymlinks
+		}
 		if f.FileInfo().IsDir() {
 			if err := os.MkdirAll(outpath, 0755); err != nil {
 				return err
@@ -275,108 +815,304 @@ func unpackZip(targetDir, archiveFile string) error {
 			continue
 		}
 
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-
 		// File
 		if err := os.MkdirAll(filepath.Dir(outpath), 0755); err != nil {
 			return err
 		}
-		out, err := os.OpenFile(outpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
-		_, err = io.Copy(out, rc)
-		rc.Close()
+		f, rel := f, rels[i]
+		err := pool.do(func() error {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+			sum, perm, err := writeFile(outpath, f.Mode().Perm(), io.TeeReader(rc, pw), int64(f.UncompressedSize64), f.Modified)
+			if err != nil {
+				return err
+			}
+			m.addFile(rel, sum, perm)
+			return nil
+		})
 		if err != nil {
-			out.Close()
-			return err
-		}
-		if err := out.Close(); err != nil {
 			return err
 		}
 	}
+	if err := pool.wait(); err != nil {
+		return err
+	}
+	if err := entries.makeSymlinks(targetDir); err != nil {
+		return err
+	}
+	pw.w.done()
 	return nil
 }
 
-// verifySHA256 reports whether the named file has contents with
-// SHA-256 of the given wantHex value.
-func verifySHA256(file, wantHex string) error {
+// readZipSymlink returns the target of the symbolic link entry f.
+func readZipSymlink(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	// Targets are limited to PATH_MAX bytes.
+	const maxTarget = 4096
+	target, err := io.ReadAll(io.LimitReader(rc, maxTarget+1))
+	if err != nil {
+		return "", fmt.Errorf("reading zip file entry %s: %v", f.Name, err)
+	}
+	if len(target) > maxTarget {
+		return "", fmt.Errorf("zip file entry %s is a symbolic link to a path longer than %d bytes", f.Name, maxTarget)
+	}
+	return string(target), nil
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of the named file's contents.
+func fileSHA256(file string) (string, error) {
 	f, err := os.Open(file)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer f.Close()
-	hash := sha256.New()
-	if _, err := io.Copy(hash, f); err != nil {
-		return err
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
 	}
-	if fmt.Sprintf("%x", hash.Sum(nil)) != wantHex {
-		return fmt.Errorf("%s corrupt? does not have expected SHA-256 of %v", file, wantHex)
+	hash := sha256.New()
+	pw := newProgressWriter(hash, phaseVerifying, 0, fi.Size())
+	if _, err := io.Copy(pw, f); err != nil {
+		return "", err
 	}
-	return nil
+	pw.done()
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
 // slurpURLToString downloads the given URL and returns it as a string.
-func slurpURLToString(url_ string) (string, error) {
-	res, err := http.Get(url_)
+func slurpURLToString(url_ string) (slurp string, err error) {
+	c, err := httpClient()
 	if err != nil {
 		return "", err
 	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("%s: %v", url_, res.Status)
+	err = retry("fetching "+url_, func() error {
+		res, err := c.Get(url_)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return newStatusError(res, fmt.Errorf("%s: %v", url_, res.Status))
+		}
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", url_, err)
+		}
+		slurp = string(b)
+		return nil
+	})
+	return slurp, err
+}
+
+// copyFromURL downloads srcURL to dstFile, and returns the hex-encoded
+// SHA-256 of its content. If tee is not nil, the content is also written
+// to it as it is downloaded. If dstFile is empty, the content is only
+// written to tee.
+//
+// The download is written to a partial file next to dstFile, which is
+// renamed into place once complete. If the server supports range requests,
+// an interrupted download leaves the partial file behind along with the
+// validator (ETag or Last-Modified) of the response it came from, and the
+// next call resumes it with a conditional range request. If the file on the
+// server has changed in the meantime, the server replies with the full
+// content and the download starts over. When resuming, the content of the
+// partial file is read back to be hashed and written to tee first.
+func copyFromURL(dstFile, srcURL string, tee io.Writer) (sum string, err error) {
+	var partFile, validatorFile string
+	var offset int64
+	var validator string
+	if dstFile != "" {
+		partFile = dstFile + partialSuffix
+		validatorFile = partFile + validatorSuffix
+		offset, validator = partialDownload(partFile, validatorFile)
 	}
-	slurp, err := io.ReadAll(res.Body)
+
+	_, _, idle := downloadTimeouts()
+	ctx, stalls := watchStalls(context.Background(), idle)
+	defer stalls.stop()
+	req, err := http.NewRequestWithContext(ctx, "GET", srcURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("reading %s: %v", url_, err)
+		return "", err
 	}
-	return string(slurp), nil
-}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+	c, err := httpClient()
+	if err != nil {
+		return "", err
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
 
-// copyFromURL downloads srcURL to dstFile.
-func copyFromURL(dstFile, srcURL string) (err error) {
-	f, err := os.Create(dstFile)
+	var f *os.File
+	resumable := false
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(res.Header.Get("Content-Range")); !ok || start != offset {
+			return "", fmt.Errorf("server returned range %q; want bytes from %d", res.Header.Get("Content-Range"), offset)
+		}
+		log.Printf("Resuming download at %s", fmtSize(offset))
+		f, err = os.OpenFile(partFile, os.O_WRONLY|os.O_APPEND, 0)
+		resumable = true
+	case http.StatusOK:
+		if offset > 0 {
+			log.Printf("Server can't resume the partial download; restarting")
+		}
+		offset = 0
+		if dstFile == "" {
+			break
+		}
+		os.Remove(validatorFile)
+		f, err = os.Create(partFile)
+		if err == nil {
+			if v := responseValidator(res); v != "" {
+				resumable = os.WriteFile(validatorFile, []byte(v), 0644) == nil
+			}
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			// No range was requested.
+			return "", newStatusError(res, errors.New(res.Status))
+		}
+		// The partial file is as long as, or longer than, the file on
+		// the server. It can't be trusted; start over.
+		if err := os.Remove(partFile); err != nil {
+			return "", err
+		}
+		os.Remove(validatorFile)
+		return copyFromURL(dstFile, srcURL, tee)
+	default:
+		return "", newStatusError(res, errors.New(res.Status))
+	}
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer func() {
-		if err != nil {
+		if err != nil && f != nil {
 			f.Close()
-			os.Remove(dstFile)
+			if !resumable {
+				discardPartialDownload(partFile, validatorFile)
+			}
 		}
 	}()
-	c := &http.Client{
-		Transport: &userAgentTransport{&http.Transport{
-			// It's already compressed. Prefer accurate ContentLength.
-			// (Not that GCS would try to compress it, though)
-			DisableCompression: true,
-			DisableKeepAlives:  true,
-			Proxy:              http.ProxyFromEnvironment,
-		}},
-	}
-	res, err := c.Get(srcURL)
+
+	hash := sha256.New()
+	w := []io.Writer{stalls, hash}
+	if tee != nil {
+		w = append(w, tee)
+	}
+	if offset > 0 {
+		if err := copyPartialDownload(io.MultiWriter(w...), partFile, offset); err != nil {
+			return "", err
+		}
+	}
+	if f != nil {
+		w = append(w, f)
+	}
+	total := res.ContentLength
+	if total != -1 {
+		total += offset
+	}
+	pw := newProgressWriter(io.MultiWriter(w...), phaseDownloading, offset, total)
+	stalls.start()
+	n, err := io.Copy(pw, limitReader(res.Body, downloadLimiter()))
 	if err != nil {
-		return err
+		return "", stalls.check(err, pw.n, total)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return errors.New(res.Status)
+	if res.ContentLength != -1 && res.ContentLength != n {
+		return "", fmt.Errorf("copied %v bytes; expected %v", n, res.ContentLength)
+	}
+	pw.done()
+	sum = fmt.Sprintf("%x", hash.Sum(nil))
+	if f == nil {
+		return sum, nil
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(partFile, dstFile); err != nil {
+		return "", err
 	}
-	pw := &progressWriter{w: f, total: res.ContentLength, output: os.Stderr}
-	n, err := io.Copy(pw, res.Body)
+	os.Remove(validatorFile)
+	return sum, nil
+}
+
+// copyPartialDownload writes the first n bytes of partFile to w.
+func copyPartialDownload(w io.Writer, partFile string, n int64) error {
+	f, err := os.Open(partFile)
 	if err != nil {
 		return err
 	}
-	if res.ContentLength != -1 && res.ContentLength != n {
-		return fmt.Errorf("copied %v bytes; expected %v", n, res.ContentLength)
+	defer f.Close()
+	if _, err := io.CopyN(w, f, n); err != nil {
+		return fmt.Errorf("reading partial download: %v", err)
+	}
+	return nil
+}
+
+// partialDownload returns the size of a previously interrupted download in
+// partFile and the validator it was saved with. It returns a zero size if
+// there is nothing to resume.
+func partialDownload(partFile, validatorFile string) (size int64, validator string) {
+	fi, err := os.Stat(partFile)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 {
+		return 0, ""
+	}
+	v, err := os.ReadFile(validatorFile)
+	if err != nil || len(bytes.TrimSpace(v)) == 0 {
+		return 0, ""
 	}
-	pw.update() // 100%
-	return f.Close()
+	return fi.Size(), string(bytes.TrimSpace(v))
 }
 
+// discardPartialDownload removes the files left behind by an interrupted
+// download.
+func discardPartialDownload(partFile, validatorFile string) {
+	os.Remove(partFile)
+	os.Remove(validatorFile)
+}
+
+// responseValidator returns the value to send in an If-Range header to
+// resume the download of res, or the empty string if the server doesn't
+// support resuming it.
+func responseValidator(res *http.Response) string {
+	if res.Header.Get("Accept-Ranges") != "bytes" {
+		return ""
+	}
+	// If-Range requires a strong validator.
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return res.Header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the first byte position of a Content-Range
+// header value of the form "bytes first-last/length".
+func contentRangeStart(contentRange string) (int64, bool) {
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(strings.TrimPrefix(contentRange, "bytes "), "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(first, 10, 64)
+	return n, err == nil
+}
+
+// A progressWriter counts the bytes written through it to report the
+// progress of a phase of install.
 type progressWriter struct {
 	w         io.Writer
 	n         int64
@@ -384,22 +1120,59 @@ type progressWriter struct {
 	last      time.Time
 	formatted bool
 	output    io.Writer
+
+	phase  string           // phaseDownloading if empty
+	render progressRenderer // if nil, a plainRenderer to output
+
+	// If began is not zero, the rate of the phase since then, when beganN
+	// bytes had been written, is reported as well.
+	began  time.Time
+	beganN int64
 }
 
-func (p *progressWriter) update() {
-	end := " ..."
-	if p.n == p.total {
-		end = ""
+// newProgressWriter returns a progressWriter writing to w, reporting the
+// progress of phase with the renderer selected by the user. The first n of
+// total bytes are already done.
+func newProgressWriter(w io.Writer, phase string, n, total int64) *progressWriter {
+	p := &progressWriter{w: w, n: n, total: total, phase: phase, render: newProgressRenderer()}
+	p.start(time.Now())
+	return p
+}
+
+// start records the start of the phase, to report its rate.
+func (p *progressWriter) start(now time.Time) {
+	p.began, p.beganN = now, p.n
+}
+
+// state returns the current progress.
+func (p *progressWriter) state() progressState {
+	s := progressState{phase: p.phase, n: p.n, total: p.total, rate: -1}
+	if s.phase == "" {
+		s.phase = phaseDownloading
 	}
-	if p.formatted {
-		fmt.Fprintf(p.output, "Downloaded %5.1f%% (%s / %s)%s\n",
-			(100.0*float64(p.n))/float64(p.total),
-			fmtSize(p.n), fmtSize(p.total), end)
-	} else {
-		fmt.Fprintf(p.output, "Downloaded %5.1f%% (%*d / %d bytes)%s\n",
-			(100.0*float64(p.n))/float64(p.total),
-			ndigits(p.total), p.n, p.total, end)
+	if !p.began.IsZero() {
+		s.elapsed = time.Since(p.began)
+		if secs := s.elapsed.Seconds(); secs > 0 {
+			s.rate = float64(p.n-p.beganN) / secs
+		}
 	}
+	return s
+}
+
+func (p *progressWriter) renderer() progressRenderer {
+	if p.render == nil {
+		return &plainRenderer{output: p.output, formatted: p.formatted}
+	}
+	return p.render
+}
+
+func (p *progressWriter) update() {
+	p.renderer().update(p.state())
+}
+
+// done reports that the phase is complete.
+func (p *progressWriter) done() {
+	p.renderer().done(p.state())
 }
 
 func ndigits(i int64) int {
@@ -426,23 +1199,27 @@ func getOS() string {
 	return runtime.GOOS
 }
 
-// versionArchiveURL returns the zip or tar.gz URL of the given Go version.
-func versionArchiveURL(version string) string {
-	goos := getOS()
-
-	ext := ".tar.gz"
-	if goos == "windows" {
-		ext = ".zip"
-	}
-	arch := runtime.GOARCH
-	if goos == "linux" && runtime.GOARCH == "arm" {
-		arch = "armv6l"
-	}
-	return "https://dl.google.com/go/" + version + "." + goos + "-" + arch + ext
+// versionArchiveName returns the file name of the zip or tar.gz archive of
+// the given Go version for the current platform.
+func versionArchiveName(version string) string {
+	return hostPlatform().archiveName(version)
 }
 
 const caseInsensitiveEnv = runtime.GOOS == "windows"
 
+// partialSuffix is appended to the name of an archive while it is being
+// downloaded, and validatorSuffix to that of the partial file to name the
+// file recording the ETag or Last-Modified value of the response it was
+// downloaded from.
+const (
+	partialSuffix   = ".partial"
+	validatorSuffix = ".validator"
+)
+
+// stagingSuffix is appended to the SDK directory to name the directory in
+// which it is installed before being renamed into place.
+const stagingSuffix = ".staging"
+
 // unpackedOkay is a sentinel zero-byte file to indicate that the Go
 // version was downloaded and unpacked successfully.
 const unpackedOkay = ".unpacked-success"
@@ -454,12 +1231,14 @@ func exe() string {
 	return ""
 }
 
+// goroot returns the GOROOT of the given version, in the directory
+// returned by sdkRoot.
 func goroot(version string) (string, error) {
-	home, err := homedir()
+	root, err := sdkRoot()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %v", err)
+		return "", err
 	}
-	return filepath.Join(home, "sdk", version), nil
+	return filepath.Join(root, version), nil
 }
 
 func homedir() (string, error) {
@@ -486,13 +1265,6 @@ func homedir() (string, error) {
 	}
 }
 
-func validRelPath(p string) bool {
-	if p == "" || strings.Contains(p, `\`) || strings.HasPrefix(p, "/") || strings.Contains(p, "../") {
-		return false
-	}
-	return true
-}
-
 type userAgentTransport struct {
 	rt http.RoundTripper
 }
@@ -6,12 +6,52 @@ package version
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
+func TestMain(m *testing.M) {
+	// Keep the tests out of the user's cache, and don't let archives cached
+	// by one test be used by another, unless it asks for it.
+	dir, err := os.MkdirTemp("", "godl-cache")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("GODL_CACHE", dir)
+	os.Setenv("GODL_CACHE_MAX_SIZE", "0")
+	// Installs are read-only by default. Tests that need to modify the
+	// installed SDKs set GODL_READONLY=0 themselves.
+	os.Unsetenv("GODL_READONLY")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// tempSDKDir returns a new temporary directory to install SDKs to. As
+// installed SDKs are read-only, it is removed with removeAll at the end of
+// the test.
+func tempSDKDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Cleanup(func() {
+		if err := removeAll(dir); err != nil {
+			t.Error(err)
+		}
+	})
+	return dir
+}
+
 func TestDedupEnv(t *testing.T) {
 	tests := []struct {
 		noCase bool
@@ -119,3 +159,258 @@ func TestComputeEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestCopyFromURLResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	var requests []string
+	interrupt := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if interrupt {
+			// Send half of the file, then drop the connection.
+			interrupt = false
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "go.tar.gz")
+	if _, err := copyFromURL(dst, ts.URL, nil); err == nil {
+		t.Fatal("copyFromURL succeeded on interrupted download")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("interrupted download created %s", dst)
+	}
+	if fi, err := os.Stat(dst + partialSuffix); err != nil || fi.Size() != int64(len(content)/2) {
+		t.Fatalf("partial download not kept: %v", err)
+	}
+
+	if _, err := copyFromURL(dst, ts.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("resumed download has wrong content")
+	}
+	if want := fmt.Sprintf("bytes=%d-", len(content)/2); len(requests) != 2 || requests[1] != want {
+		t.Errorf("Range headers = %q; want second request to send %q", requests, want)
+	}
+	for _, f := range []string{dst + partialSuffix, dst + partialSuffix + validatorSuffix} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s not removed after download", f)
+		}
+	}
+}
+
+func TestCopyFromURLResumeChanged(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefghij"), 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dst := filepath.Join(t.TempDir(), "go.tar.gz")
+	if err := os.WriteFile(dst+partialSuffix, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst+partialSuffix+validatorSuffix, []byte(`"v1"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copyFromURL(dst, ts.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("download of changed file has wrong content")
+	}
+}
+
+func TestCopyFromURLRangeNotSatisfiable(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	defer ts.Close()
+
+	// A partial file too long for the server is discarded once.
+	dst := filepath.Join(t.TempDir(), "go.tar.gz")
+	if err := os.WriteFile(dst+partialSuffix, []byte("0123456789abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst+partialSuffix+validatorSuffix, []byte(`"v1"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copyFromURL(dst, ts.URL, nil); err == nil {
+		t.Fatal("copyFromURL succeeded on 416 response to request without range")
+	}
+	if requests != 2 {
+		t.Errorf("copyFromURL made %d requests; want 2", requests)
+	}
+	if _, err := os.Stat(dst + partialSuffix); !os.IsNotExist(err) {
+		t.Errorf("partial download not discarded: %v", err)
+	}
+}
+
+func TestInstallStreaming(t *testing.T) {
+	if strings.HasSuffix(versionArchiveName("go1.99"), ".zip") {
+		t.Skip("zip archives are not unpacked as they download")
+	}
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99", "bin/go": "go"})
+	mirror := httptest.NewServer(serveArchive(archive, true))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+	t.Setenv("GODL_KEEP_ARCHIVE", "0")
+
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"VERSION", "bin/go", unpackedOkay} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, versionArchiveName("go1.99"))); !os.IsNotExist(err) {
+		t.Errorf("archive kept with GODL_KEEP_ARCHIVE=0")
+	}
+	if _, err := os.Stat(dir + stagingSuffix); !os.IsNotExist(err) {
+		t.Errorf("staging directory not removed")
+	}
+}
+
+func TestInstallStreamingCorrupt(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	other := testArchive(t, map[string]string{"VERSION": "go1.98"})
+	mirror := httptest.NewServer(serveArchive(archive, false))
+	defer mirror.Close()
+	sums := httptest.NewServer(serveArchive(other, true))
+	defer sums.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+	t.Setenv("GODL_CHECKSUM_URL", sums.URL)
+
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	if err := install(dir, "go1.99", hostPlatform()); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Fatalf("install = %v; want SHA-256 mismatch", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("failed install created %s", dir)
+	}
+}
+
+func TestInstallCompiledChecksum(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	other := testArchive(t, map[string]string{"VERSION": "go1.98"})
+	// The mirror has no .sha256 files.
+	mirror := httptest.NewServer(serveArchive(other, false))
+	defer mirror.Close()
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+	defer func() { compiledSHA256 = nil }()
+
+	compiledSHA256 = map[string]string{versionArchiveName("go1.99"): fmt.Sprintf("%x", sha256.Sum256(archive))}
+	err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform())
+	if !errors.Is(err, errCompiledArchive) {
+		t.Fatalf("install = %v; want %v", err, errCompiledArchive)
+	}
+
+	compiledSHA256 = map[string]string{versionArchiveName("go1.99"): fmt.Sprintf("%x", sha256.Sum256(other))}
+	if err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallCompiledChecksumRefused(t *testing.T) {
+	archive := testArchive(t, map[string]string{"VERSION": "go1.99"})
+	defer func() { compiledSHA256 = nil }()
+	compiledSHA256 = map[string]string{versionArchiveName("go1.99"): fmt.Sprintf("%x", sha256.Sum256(nil))}
+
+	// A mirror serving the archive under a name the wrapper has no
+	// checksum for, with a matching .sha256 file.
+	name := strings.TrimSuffix(versionArchiveName("go1.99"), archiveExt(versionArchiveName("go1.99"))) + ".tar.zst"
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + name:
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(archive))
+		case "/" + name + ".sha256":
+			fmt.Fprintf(w, "%x\n", sha256.Sum256(archive))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mirror.Close()
+	t.Setenv("GODL_BUILD_SOURCE", "0")
+	t.Setenv("GODL_MIRRORS", mirror.URL)
+	err := install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform())
+	if !errors.As(err, new(*noReleaseError)) {
+		t.Errorf("install from mirror with unknown archive = %v; want no release", err)
+	}
+
+	// The toolchain module can't be verified against the archive sums.
+	t.Setenv("GODL_MIRRORS", proxyMirror)
+	err = install(filepath.Join(tempSDKDir(t), "go1.99"), "go1.99", hostPlatform())
+	if !errors.Is(err, errNoChecksum) {
+		t.Errorf("install from goproxy = %v; want %v", err, errNoChecksum)
+	}
+}
+
+func TestInstallStaged(t *testing.T) {
+	dir := filepath.Join(tempSDKDir(t), "go1.99")
+	staging := dir + stagingSuffix
+	// An earlier install was interrupted while unpacking, and an older one
+	// left an incomplete SDK behind.
+	partial := filepath.Join(staging, versionArchiveName("go1.99")+partialSuffix)
+	for _, f := range []string{filepath.Join(staging, "src", "old.go"), partial, filepath.Join(dir, "stale")} {
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errFailed := errors.New("failed")
+	err := installStaged(dir, func(staging string) error {
+		if _, err := os.Stat(partial); err != nil {
+			t.Errorf("partial download removed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(staging, "src", "old.go")); !os.IsNotExist(err) {
+			t.Errorf("stale file in staging directory not removed")
+		}
+		return errFailed
+	})
+	if err != errFailed {
+		t.Fatalf("installStaged = %v; want %v", err, errFailed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale")); err != nil {
+		t.Errorf("failed install changed %s: %v", dir, err)
+	}
+
+	err = installStaged(dir, func(staging string) error {
+		return os.WriteFile(filepath.Join(staging, unpackedOkay), nil, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, unpackedOkay)); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale")); !os.IsNotExist(err) {
+		t.Errorf("stale file left in %s", dir)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Errorf("staging directory left behind")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultUnpackWorkers is the default number of files written concurrently
+// when unpacking an archive. Creating and writing files is dominated by
+// latency, not CPU, on network and overlay filesystems.
+const defaultUnpackWorkers = 8
+
+// maxQueuedFileSize is the size of the largest file read into memory to be
+// written by a worker while a tar archive is decompressed. Larger files
+// are written as they are decompressed, which bounds the memory held by
+// queued writes.
+const maxQueuedFileSize = 1 << 20
+
+// unpackWorkers returns the number of files to write concurrently when
+// unpacking an archive, as set by $GODL_UNPACK_WORKERS. A value of 1
+// writes them one at a time.
+func unpackWorkers() int {
+	s := os.Getenv("GODL_UNPACK_WORKERS")
+	if s == "" {
+		return defaultUnpackWorkers
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		log.Printf("ignoring invalid GODL_UNPACK_WORKERS=%q", s)
+		return defaultUnpackWorkers
+	}
+	return n
+}
+
+// A writePool runs file writes on a bounded number of goroutines. After a
+// write fails, the writes still queued are skipped and no more are queued.
+type writePool struct {
+	work      chan func() error
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func newWritePool(workers int) *writePool {
+	p := &writePool{work: make(chan func() error, workers)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for write := range p.work {
+				if p.failed() != nil {
+					continue
+				}
+				if err := write(); err != nil {
+					p.mu.Lock()
+					if p.err == nil {
+						p.err = err
+					}
+					p.mu.Unlock()
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// failed returns the error of the first failed write, if any.
+func (p *writePool) failed() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// do queues write, blocking while all workers are busy. It returns the
+// error of an earlier write instead if one failed.
+func (p *writePool) do(write func() error) error {
+	if err := p.failed(); err != nil {
+		return err
+	}
+	p.work <- write
+	return nil
+}
+
+// wait waits for the queued writes to finish and returns the error of the
+// first failed write. No more writes may be queued after wait is called.
+func (p *writePool) wait() error {
+	p.closeOnce.Do(func() { close(p.work) })
+	p.wg.Wait()
+	return p.failed()
+}
+
+// writeFile creates the file abs with the given permissions and the size
+// bytes read from r, and sets its modification time unless it is zero. It
+// returns the hex-encoded SHA-256 of the file and the permissions it was
+// created with, as recorded in a manifest.
+func writeFile(abs string, perm fs.FileMode, r io.Reader, size int64, modTime time.Time) (sum string, _ fs.FileMode, err error) {
+	wf, err := os.OpenFile(abs, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(wf, h), r)
+	if err == nil {
+		// The umask may have removed permissions.
+		var fi fs.FileInfo
+		if fi, err = wf.Stat(); err == nil {
+			perm = fi.Mode().Perm()
+		}
+	}
+	if closeErr := wf.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("error writing to %s: %v", abs, err)
+	}
+	if n != size {
+		return "", 0, fmt.Errorf("only wrote %d bytes to %s; expected %d", n, abs, size)
+	}
+	if !modTime.IsZero() {
+		if err := os.Chtimes(abs, modTime, modTime); err != nil {
+			// benign error. Gerrit doesn't even set the
+			// modtime in these, and we don't end up relying
+			// on it anywhere (the gomote push command relies
+			// on digests only), so this is a little pointless
+			// for now.
+			log.Printf("error changing modtime: %v", err)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), perm, nil
+}
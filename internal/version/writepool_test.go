@@ -0,0 +1,109 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWritePool(t *testing.T) {
+	p := newWritePool(4)
+	var done int32
+	for i := 0; i < 100; i++ {
+		if err := p.do(func() error {
+			atomic.AddInt32(&done, 1)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&done); n != 100 {
+		t.Errorf("ran %d writes; want 100", n)
+	}
+
+	// After a write fails, its error is returned instead of queuing more.
+	p = newWritePool(2)
+	errWrite := errors.New("disk full")
+	p.do(func() error { return errWrite })
+	var err error
+	for i := 0; i < 1000 && err == nil; i++ {
+		err = p.do(func() error { return nil })
+	}
+	if err != errWrite {
+		t.Errorf("do after failed write = %v; want %v", err, errWrite)
+	}
+	if err := p.wait(); err != errWrite {
+		t.Errorf("wait = %v; want %v", err, errWrite)
+	}
+}
+
+func TestUnpackConcurrent(t *testing.T) {
+	entries := []testEntry{{"go/", 0755 | os.ModeDir, "", ""}}
+	for i := 0; i < 200; i++ {
+		mode := os.FileMode(0644)
+		if i%3 == 0 {
+			mode = 0755
+		}
+		entries = append(entries, testEntry{fmt.Sprintf("go/src/pkg%d/file%d.go", i%10, i), mode, strings.Repeat(fmt.Sprint(i), i), ""})
+	}
+	// A file too large to be queued is written as it is decompressed.
+	entries = append(entries, testEntry{"go/bin/go", 0755, strings.Repeat("x", maxQueuedFileSize+1), ""})
+
+	for _, format := range []struct {
+		name   string
+		craft  func(testing.TB, []testEntry) []byte
+		unpack func(string, []byte) error
+	}{
+		{"tar.gz", craftTarGz, unpackTestTarGz},
+		{"zip", craftZip, unpackTestZip},
+	} {
+		for _, workers := range []string{"1", "8"} {
+			t.Run(format.name+"/"+workers, func(t *testing.T) {
+				t.Setenv("GODL_UNPACK_WORKERS", workers)
+				root := t.TempDir()
+				if err := format.unpack(root, format.craft(t, entries)); err != nil {
+					t.Fatal(err)
+				}
+				for _, e := range entries[1:] {
+					file := filepath.Join(root, filepath.FromSlash(e.name))
+					got, err := os.ReadFile(file)
+					if err != nil || string(got) != e.body {
+						t.Errorf("%s = %d bytes, %v; want %d bytes", e.name, len(got), err, len(e.body))
+						continue
+					}
+					if fi, err := os.Stat(file); err != nil || fi.Mode().Perm()&0100 != e.mode.Perm()&0100 {
+						t.Errorf("%s mode = %v, %v; want %v", e.name, fi.Mode(), err, e.mode)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestUnpackConcurrentWriteError(t *testing.T) {
+	t.Setenv("GODL_UNPACK_WORKERS", "4")
+	entries := []testEntry{{"go/", 0755 | os.ModeDir, "", ""}}
+	for i := 0; i < 50; i++ {
+		entries = append(entries, testEntry{fmt.Sprintf("go/file%d", i), 0644, "data", ""})
+	}
+	root := t.TempDir()
+	// A directory in the way of a file makes its write fail.
+	if err := os.MkdirAll(filepath.Join(root, "go", "file10"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := unpackTestTarGz(root, craftTarGz(t, entries))
+	if err == nil || !strings.Contains(err.Error(), "file10") {
+		t.Errorf("unpack = %v; want error writing file10", err)
+	}
+}